@@ -0,0 +1,271 @@
+package apkparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Value of android:launchMode
+type LaunchMode int
+
+const (
+	LaunchModeStandard LaunchMode = iota
+	LaunchModeSingleTop
+	LaunchModeSingleTask
+	LaunchModeSingleInstance
+	LaunchModeSingleInstancePerTask
+)
+
+var launchModeNames = []string{"standard", "singleTop", "singleTask", "singleInstance", "singleInstancePerTask"}
+
+// Returns the name used in manifest XML, e.g. "singleTop".
+func (m LaunchMode) String() string {
+	if m < 0 || int(m) >= len(launchModeNames) {
+		return "LaunchMode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return launchModeNames[m]
+}
+
+// Returns a map from class name of every activity to whether it stays in the activity stack
+// (history) after the user leaves it. It is false for activities with android:noHistory="true",
+// which is recommended for the ones showing sensitive data, like login or payment screens.
+func (a *APK) ActivityHistoryEnabled() (map[string]bool, error) {
+	res := a.componentsBoolAttr("noHistory", false, "activity")
+	for name, noHistory := range res {
+		res[name] = !noHistory
+	}
+	return res, nil
+}
+
+// Returns a map from class name of every activity to its android:allowTaskReparenting. Activities
+// which allow it can be moved to a task of another app with the same affinity (task hijacking).
+func (a *APK) TaskReparentingEnabled() (map[string]bool, error) {
+	def := false
+	if app := a.manifest.child("application"); app != nil {
+		def = app.attrOrEmpty("allowTaskReparenting") == "true"
+	}
+	return a.componentsBoolAttr("allowTaskReparenting", def, "activity"), nil
+}
+
+// Returns a map from class name of every activity to its android:launchMode. singleTask and
+// singleInstance activities are the ones exposed to task hijacking.
+func (a *APK) LaunchMode() (map[string]LaunchMode, error) {
+	res := make(map[string]LaunchMode)
+	for _, act := range a.components("activity") {
+		mode := LaunchModeStandard
+		if val, prs := act.attr("launchMode"); prs {
+			v, err := strconv.Atoi(val)
+			if err != nil {
+				// Not compiled by aapt, try the name.
+				v = -1
+				for i, name := range launchModeNames {
+					if name == val {
+						v = i
+					}
+				}
+				if v == -1 {
+					return nil, fmt.Errorf("Invalid launchMode of %s: %s", a.componentName(act), val)
+				}
+			}
+			mode = LaunchMode(v)
+		}
+		res[a.componentName(act)] = mode
+	}
+	return res, nil
+}
+
+// WindowManager.LayoutParams.FLAG_SECURE
+const windowFlagSecure = 0x2000
+
+// Returns a map from class name of every activity to whether the user (or other apps) can
+// capture its screen. It is false for activities which set FLAG_SECURE through Window.setFlags
+// or Window.addFlags in their code (or their superclass' code), and for activities with
+// android:excludeFromRecents="true", which at least keeps them out of the recents screenshots.
+func (a *APK) ScreenCaptureEnabled() (map[string]bool, error) {
+	files, err := a.dexFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	superclasses := make(map[string]string)
+	for _, d := range files {
+		for i := range d.classes {
+			if sup := d.classes[i].Superclass; sup < uint32(len(d.types)) {
+				superclasses[d.types[d.classes[i].Type]] = d.types[sup]
+			}
+		}
+	}
+
+	secure := make(map[string]bool)
+	err = a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if code.loadsInt(windowFlagSecure) &&
+			(d.invokes(code, "Landroid/view/Window;", "setFlags") || d.invokes(code, "Landroid/view/Window;", "addFlags")) {
+			secure[d.types[code.Class]] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]bool)
+	for _, act := range a.components("activity") {
+		name := a.componentName(act)
+		enabled := act.attrOrEmpty("excludeFromRecents") != "true"
+
+		// Limited depth, in case of a malformed dex with a superclass loop.
+		desc := classNameToDescriptor(name)
+		for i := 0; enabled && desc != "" && i < 32; i++ {
+			if secure[desc] {
+				enabled = false
+			}
+			desc = superclasses[desc]
+		}
+		res[name] = enabled
+	}
+	return res, nil
+}
+
+// Returns a map from class name of every activity to its android:resizableActivity (multi-window support).
+// Unset values are taken from <application>, and default to true for apps targeting SDK 24 or later
+// (which includes all SDK 31+ apps), false otherwise.
+func (a *APK) ResizableActivity() (map[string]bool, error) {
+	def := a.applicationBoolAttr("resizableActivity", a.targetSdkVersion() >= 24)
+	return a.componentsBoolAttr("resizableActivity", def, "activity"), nil
+}
+
+// Returns class names of activities with android:supportsPictureInPicture="true" (SDK 26+).
+// Such activities should also handle onPictureInPictureModeChanged, that is not checked.
+func (a *APK) PictureInPictureSupport() ([]string, error) {
+	var res []string
+	for _, act := range a.components("activity") {
+		if act.attrOrEmpty("supportsPictureInPicture") == "true" {
+			res = append(res, a.componentName(act))
+		}
+	}
+	return res, nil
+}
+
+// Values of android:screenOrientation, starting at -1.
+var screenOrientationNames = []string{
+	"unspecified", "landscape", "portrait", "user", "behind", "sensor", "nosensor", "sensorLandscape",
+	"sensorPortrait", "reverseLandscape", "reversePortrait", "fullSensor", "userLandscape",
+	"userPortrait", "fullUser", "locked",
+}
+
+// Returns a map from class name of every activity to its android:screenOrientation, e.g. "portrait",
+// "landscape" or "sensor". Activities without it have "unspecified".
+func (a *APK) LockedOrientations() (map[string]string, error) {
+	res := make(map[string]string)
+	for _, act := range a.components("activity") {
+		orientation := "unspecified"
+		if val, prs := act.attr("screenOrientation"); prs {
+			if v, err := strconv.ParseInt(val, 0, 32); err != nil {
+				// Not compiled by aapt, keep the name.
+				orientation = val
+			} else if v+1 >= 0 && v+1 < int64(len(screenOrientationNames)) {
+				orientation = screenOrientationNames[v+1]
+			} else {
+				return nil, fmt.Errorf("Invalid screenOrientation of %s: %s", a.componentName(act), val)
+			}
+		}
+		res[a.componentName(act)] = orientation
+	}
+	return res, nil
+}
+
+type attrFlag struct {
+	Name  string
+	Value uint32
+}
+
+// Flags of android:configChanges, from attrs_manifest.xml.
+var configChangesFlags = []attrFlag{
+	{"mcc", 0x0001},
+	{"mnc", 0x0002},
+	{"locale", 0x0004},
+	{"touchscreen", 0x0008},
+	{"keyboard", 0x0010},
+	{"keyboardHidden", 0x0020},
+	{"navigation", 0x0040},
+	{"orientation", 0x0080},
+	{"screenLayout", 0x0100},
+	{"uiMode", 0x0200},
+	{"screenSize", 0x0400},
+	{"smallestScreenSize", 0x0800},
+	{"density", 0x1000},
+	{"layoutDirection", 0x2000},
+	{"colorMode", 0x4000},
+	{"grammaticalGender", 0x8000},
+	{"fontWeightAdjustment", 0x10000000},
+	{"fontScale", 0x40000000},
+}
+
+// Decodes the flags attribute value (aapt stores them as hex int) into flag names.
+// Unknown bits are returned as one hex value.
+func decodeAttrFlags(val string, flags []attrFlag) ([]string, error) {
+	v, err := strconv.ParseUint(val, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, f := range flags {
+		if uint32(v)&f.Value != 0 {
+			res = append(res, f.Name)
+			v &^= uint64(f.Value)
+		}
+	}
+
+	if v != 0 {
+		res = append(res, fmt.Sprintf("0x%x", v))
+	}
+	return res, nil
+}
+
+// Returns a map from class name of every activity to the configuration changes it handles itself
+// (android:configChanges), e.g. "orientation", "keyboardHidden", "screenSize". The activity
+// is not restarted on these changes.
+func (a *APK) ConfigChanges() (map[string][]string, error) {
+	res := make(map[string][]string)
+	for _, act := range a.components("activity") {
+		var changes []string
+		if val, prs := act.attr("configChanges"); prs {
+			var err error
+			if changes, err = decodeAttrFlags(val, configChangesFlags); err != nil {
+				return nil, fmt.Errorf("Invalid configChanges of %s: %s", a.componentName(act), val)
+			}
+		}
+		res[a.componentName(act)] = changes
+	}
+	return res, nil
+}
+
+var (
+	// Values of the state (mask 0x0f) and adjust (mask 0xf0) parts of android:windowSoftInputMode.
+	softInputStates = []string{"stateUnspecified", "stateUnchanged", "stateHidden", "stateAlwaysHidden", "stateVisible", "stateAlwaysVisible"}
+	softInputAdjust = []string{"adjustUnspecified", "adjustResize", "adjustPan", "adjustNothing"}
+)
+
+// Returns a map from class name of every activity to its decoded android:windowSoftInputMode,
+// e.g. ["stateHidden", "adjustResize"]. The unspecified parts are left out.
+func (a *APK) WindowSoftInputMode() (map[string][]string, error) {
+	res := make(map[string][]string)
+	for _, act := range a.components("activity") {
+		var mode []string
+		if val, prs := act.attr("windowSoftInputMode"); prs {
+			v, err := strconv.ParseUint(val, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid windowSoftInputMode of %s: %s", a.componentName(act), val)
+			}
+
+			if state := v & 0x0f; state != 0 && state < uint64(len(softInputStates)) {
+				mode = append(mode, softInputStates[state])
+			}
+			if adjust := (v & 0xf0) >> 4; adjust != 0 && adjust < uint64(len(softInputAdjust)) {
+				mode = append(mode, softInputAdjust[adjust])
+			}
+		}
+		res[a.componentName(act)] = mode
+	}
+	return res, nil
+}
@@ -0,0 +1,639 @@
+package apkparser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returned by APK methods when the APK doesn't contain the requested information.
+var ErrNotFound = errors.New("Not found")
+
+// Returned by methods which need the whole APK, when called on one not made by OpenAPK, NewAPK
+// or the unmarshal methods, like zero-value APK{}.
+var errNoManifest = errors.New("The APK has no parsed manifest.")
+
+// Opened APK with parsed AndroidManifest.xml and resources.arsc, for querying
+// information about the app without writing an encoder.
+type APK struct {
+	zip     *ZipReader
+	ownsZip bool
+
+	resources    *ResourceTable
+	resourcesErr error
+
+	manifest *xmlElement
+
+	dex    []*dexFile
+	dexErr error
+
+	ctx context.Context
+
+	// Bytes read from the zip while parsing, set only in newAPK.
+	parseRead *int64
+	// Upper bound of memory used by the parse in newAPK, see MemStats.PeakBytes.
+	parsePeak int64
+}
+
+// Opens the APK at path and parses its manifest and resources. Close() the APK when done.
+func OpenAPK(path string) (*APK, error) {
+	return openAPK(nil, path)
+}
+
+// Like OpenAPK, but if ctx is not nil, the APK is bound to it (see WithContext) already
+// while parsing the manifest and resources.
+func openAPK(ctx context.Context, path string) (*APK, error) {
+	zip, err := OpenZip(path)
+	if err != nil {
+		return nil, err
+	}
+
+	apk, err := newAPK(ctx, zip)
+	if err != nil {
+		zip.Close()
+		return nil, err
+	}
+	apk.ownsZip = true
+	return apk, nil
+}
+
+// Parses the manifest and resources from already opened zip. Closing the APK will not
+// Close() the zip, you are still the owner.
+//
+// Failing to parse resources.arsc is not fatal, the manifest is then parsed without
+// reference resolving. See ResourcesError().
+func NewAPK(zip *ZipReader) (*APK, error) {
+	return newAPK(nil, zip)
+}
+
+func newAPK(ctx context.Context, zip *ZipReader) (*APK, error) {
+	a := &APK{
+		zip: zip,
+		ctx: ctx,
+	}
+
+	var parseRead int64
+	a.parseRead = &parseRead
+
+	p := apkParser{zip: zip, wrapReader: a.reader}
+	if err := p.parseResources(); err != nil {
+		if ctxErr := a.ctxErr(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		a.resourcesErr = err
+	}
+	a.resources = p.resources
+
+	var err error
+	if a.manifest, err = a.parseXml("AndroidManifest.xml"); err != nil {
+		return nil, err
+	}
+
+	if a.manifest.Name != "manifest" {
+		return nil, fmt.Errorf("Invalid manifest root element: %s", a.manifest.Name)
+	}
+
+	a.parseRead = nil
+	a.parsePeak = a.MemoryStats().total() + parseRead
+	return a, nil
+}
+
+// Closes the underlying zip, if it was opened by OpenAPK.
+func (a *APK) Close() error {
+	if !a.ownsZip {
+		return nil
+	}
+	return a.zip.Close()
+}
+
+// Returns a copy of the APK whose methods stop reading files from the zip (dex files, XML resources...)
+// and return ctx.Err() once ctx is done. The manifest and resources.arsc are parsed in OpenAPK/NewAPK,
+// so they are not affected. Only the original APK has to be closed.
+func (a *APK) WithContext(ctx context.Context) *APK {
+	c := *a
+	c.ctx = ctx
+	c.ownsZip = false
+	return &c
+}
+
+// Returns a deep copy of the parsed data (manifest, resources and already parsed dex files),
+// the copy and the original can be modified independently. They share the underlying zip,
+// which stays owned by the original APK, so don't use the copy after closing the original.
+func (a *APK) Clone() (*APK, error) {
+	if a.manifest == nil {
+		return nil, errNoManifest
+	}
+
+	c := &APK{
+		zip:          a.zip,
+		resourcesErr: a.resourcesErr,
+		manifest:     a.manifest.clone(nil),
+		dexErr:       a.dexErr,
+		ctx:          a.ctx,
+		parsePeak:    a.parsePeak,
+	}
+
+	if a.resources != nil {
+		c.resources = a.resources.clone()
+	}
+
+	if a.dex != nil {
+		c.dex = make([]*dexFile, len(a.dex))
+		for i, d := range a.dex {
+			c.dex[i] = d.clone()
+		}
+	}
+	return c, nil
+}
+
+// Returns r wrapped to check the APK's context, if it has one.
+func (a *APK) reader(r io.Reader) io.Reader {
+	if a.parseRead != nil {
+		r = &countingReader{r: r, n: a.parseRead}
+	}
+
+	if a.ctx == nil {
+		return r
+	}
+	return &contextReader{ctx: a.ctx, r: r, sinceCheck: contextCheckInterval}
+}
+
+// Reader which adds the number of bytes read to n.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
+
+// Returns ctx.Err() of the APK's context, nil if it has none.
+func (a *APK) ctxErr() error {
+	if a.ctx == nil {
+		return nil
+	}
+	return a.ctx.Err()
+}
+
+// Bytes read between context checks
+const contextCheckInterval = 64 * 1024
+
+// Reader which returns ctx.Err() once the context is done, checked every contextCheckInterval bytes.
+type contextReader struct {
+	ctx        context.Context
+	r          io.Reader
+	sinceCheck int
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if r.sinceCheck >= contextCheckInterval {
+		if err := r.ctx.Err(); err != nil {
+			return 0, err
+		}
+		r.sinceCheck = 0
+	}
+
+	n, err := r.r.Read(p)
+	r.sinceCheck += n
+	return n, err
+}
+
+// Returns the string pool of the binary AndroidManifest.xml: element and attribute names,
+// namespaces and string attribute values, in the order they are stored.
+func (a *APK) StringPool() (*StringTable, error) {
+	data, err := a.readFile("AndroidManifest.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := parseXmlStringPool(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse AndroidManifest.xml: %s", err.Error())
+	}
+	return res, nil
+}
+
+// Returns the global string pool of resources.arsc, which has the values of all string resources
+// and the paths of file resources. If the APK has no usable resources.arsc, returns the error from ResourcesError.
+func (a *APK) ResourceStringPool() (*StringTable, error) {
+	if a.resources == nil {
+		return nil, a.resourcesErr
+	}
+	return &a.resources.mainStrings, nil
+}
+
+// Returns the error from parsing resources.arsc, os.ErrNotExist if the APK has none.
+func (a *APK) ResourcesError() error {
+	return a.resourcesErr
+}
+
+func (a *APK) parseXml(name string) (*xmlElement, error) {
+	return a.parseXmlWithResources(name, a.resources)
+}
+
+// Parses a binary XML file from the zip, references are resolved through resources if not nil,
+// otherwise they are kept as "@" and the hex resource id.
+func (a *APK) parseXmlWithResources(name string, resources *ResourceTable) (*xmlElement, error) {
+	file := a.zip.File[name]
+	if file == nil {
+		return nil, fmt.Errorf("Failed to find %s!", name)
+	}
+
+	if err := file.Open(); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lastErr error
+	for file.Next() {
+		enc := &xmlTreeEncoder{}
+		if err := ParseManifest(a.reader(file), enc, resources); err != nil {
+			if ctxErr := a.ctxErr(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			lastErr = err
+		} else if enc.root == nil {
+			lastErr = fmt.Errorf("No elements found.")
+		} else {
+			return enc.root, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Failed to parse %s, last error: %v", name, lastErr)
+}
+
+func (a *APK) packageName() string {
+	return a.manifest.attrOrEmpty("package")
+}
+
+// Returns the fully qualified class name of the component, resolving names
+// relative to the package like ".MainActivity".
+func (a *APK) componentName(el *xmlElement) string {
+	return a.className(el.attrOrEmpty("name"))
+}
+
+// Returns the fully qualified class name, resolving names relative to the package.
+func (a *APK) className(name string) string {
+	if strings.HasPrefix(name, ".") {
+		return a.packageName() + name
+	} else if name != "" && !strings.Contains(name, ".") {
+		return a.packageName() + "." + name
+	}
+	return name
+}
+
+// Returns android:targetSdkVersion from <uses-sdk>, which defaults to minSdkVersion and that to 1.
+// Preview codenames like "Q" are in development, so they are above any released version.
+func (a *APK) targetSdkVersion() int {
+	usesSdk := a.manifest.child("uses-sdk")
+	if usesSdk == nil {
+		return 1
+	}
+
+	for _, name := range []string{"targetSdkVersion", "minSdkVersion"} {
+		val, prs := usesSdk.attr(name)
+		if !prs {
+			continue
+		}
+
+		if v, err := strconv.Atoi(val); err == nil {
+			return v
+		}
+		return 10000
+	}
+	return 1
+}
+
+// Returns true if the component can be started by other apps. Without android:exported,
+// components with an intent filter are exported, as are providers in apps targeting SDK < 17.
+func (a *APK) isExported(component *xmlElement) bool {
+	if val, prs := component.attr("exported"); prs {
+		return val == "true"
+	}
+
+	if component.Name == "provider" {
+		return a.targetSdkVersion() < 17
+	}
+	return len(component.children("intent-filter")) != 0
+}
+
+// Returns android:permission of the component, or of the <application> if the component has none.
+func (a *APK) componentPermission(component *xmlElement) string {
+	if perm, prs := component.attr("permission"); prs {
+		return perm
+	}
+
+	if app := a.manifest.child("application"); app != nil {
+		return app.attrOrEmpty("permission")
+	}
+	return ""
+}
+
+// Returns true if any of the component's <intent-filter>s has this action.
+func hasIntentFilterAction(component *xmlElement, action string) bool {
+	for _, filter := range component.children("intent-filter") {
+		for _, act := range filter.children("action") {
+			if act.attrOrEmpty("name") == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns names from all <uses-permission> and <uses-permission-sdk-23> elements.
+func (a *APK) usesPermissions() []string {
+	var res []string
+	for _, el := range a.manifest.Children {
+		if el.Name != "uses-permission" && el.Name != "uses-permission-sdk-23" {
+			continue
+		}
+
+		if name := el.attrOrEmpty("name"); name != "" {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// Reads the whole file from the zip, from the first of its entries that can be read.
+func (a *APK) readFile(name string) ([]byte, error) {
+	file := a.zip.File[name]
+	if file == nil {
+		return nil, os.ErrNotExist
+	}
+
+	if err := file.Open(); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lastErr error
+	for file.Next() {
+		data, err := ioutil.ReadAll(a.reader(file))
+		if err == nil {
+			return data, nil
+		} else if ctxErr := a.ctxErr(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("Failed to read %s, last error: %v", name, lastErr)
+}
+
+// Returns the parsed classes.dex, classes2.dex... files. Like Android, this stops
+// at the first missing classesN.dex.
+func (a *APK) dexFiles() ([]*dexFile, error) {
+	if a.dex != nil || a.dexErr != nil {
+		return a.dex, a.dexErr
+	}
+
+	for i := 1; ; i++ {
+		name := "classes.dex"
+		if i > 1 {
+			name = "classes" + strconv.Itoa(i) + ".dex"
+		}
+
+		if a.zip.File[name] == nil {
+			break
+		}
+
+		data, err := a.readFile(name)
+		if err != nil {
+			a.dex = nil
+			if err != a.ctxErr() {
+				a.dexErr = err
+			}
+			return nil, err
+		}
+
+		d, err := parseDex(name, data)
+		if err != nil {
+			a.dexErr = err
+			return nil, err
+		}
+		a.dex = append(a.dex, d)
+	}
+
+	if a.dex == nil {
+		a.dex = []*dexFile{}
+	}
+	return a.dex, nil
+}
+
+// Returns true if any of the dex files references a class that is or is in the prefix,
+// see dexFile.hasTypePrefix.
+func (a *APK) hasDexClass(prefix string) (bool, error) {
+	files, err := a.dexFiles()
+	if err != nil {
+		return false, err
+	}
+
+	for _, d := range files {
+		if d.hasTypePrefix(prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns android:value (or android:resource, if it has no value) of the <meta-data>
+// with this name in <application>.
+func (a *APK) metaData(name string) (string, bool) {
+	app := a.manifest.child("application")
+	if app == nil {
+		return "", false
+	}
+	return elementMetaData(app, name)
+}
+
+// Returns android:value (or android:resource, if it has no value) of the <meta-data>
+// with this name which is a direct child of el.
+func elementMetaData(el *xmlElement, name string) (string, bool) {
+	for _, md := range el.children("meta-data") {
+		if md.attrOrEmpty("name") != name {
+			continue
+		}
+
+		if val, prs := md.attr("value"); prs {
+			return val, true
+		}
+		return md.attr("resource")
+	}
+	return "", false
+}
+
+// Returns the value of string resource with this name, resolving references.
+func (a *APK) stringResource(name string) (string, error) {
+	if a.resources == nil {
+		return "", ErrNotFound
+	}
+
+	id, err := a.resources.GetResourceId("string", name)
+	if err != nil {
+		return "", ErrNotFound
+	}
+
+	entry, err := a.resources.GetResourceEntry(id)
+	for i := 0; err == nil && entry.value.dataType == AttrTypeReference && i < 5; i++ {
+		entry, err = a.resources.GetResourceEntry(entry.value.data)
+	}
+	if err != nil {
+		return "", err
+	}
+	return entry.value.String(), nil
+}
+
+// Returns the resource id from unresolved reference "@7f0a0001", or 0.
+func referenceId(val string) uint32 {
+	if !strings.HasPrefix(val, "@") {
+		return 0
+	}
+
+	id, err := strconv.ParseUint(val[1:], 16, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(id)
+}
+
+// Returns the value of unresolved reference like "@7f0a0001", or val itself if it is not a reference
+// or can't be resolved.
+func (a *APK) resolveReference(val string) string {
+	id := referenceId(val)
+	if id == 0 || a.resources == nil {
+		return val
+	}
+
+	entry, err := a.resources.GetResourceEntry(id)
+	for i := 0; err == nil && entry.value.dataType == AttrTypeReference && i < 5; i++ {
+		entry, err = a.resources.GetResourceEntry(entry.value.data)
+	}
+	if err != nil {
+		return val
+	}
+	return entry.value.String()
+}
+
+// Returns the version from META-INF/<group>_<artifact>.version file, which gradle puts
+// into the APK for some libraries (all of Google's, for example).
+func (a *APK) libraryVersion(library string) (string, bool) {
+	data, err := a.readFile("META-INF/" + library + ".version")
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+var densityQualifiers = map[string]int{
+	"ldpi":    120,
+	"mdpi":    160,
+	"tvdpi":   213,
+	"hdpi":    240,
+	"xhdpi":   320,
+	"xxhdpi":  480,
+	"xxxhdpi": 640,
+}
+
+// Returns the screen densities (in dpi) this APK targets. Density split APKs
+// (split_config.xhdpi.apk etc.) return the one density from their split name,
+// other APKs return all densities that have a resource config in resources.arsc.
+//
+// Resources for any density (nodpi, anydpi) and the default config are not included.
+func (a *APK) DensityTargeting() ([]int, error) {
+	if split := a.manifest.attrOrEmpty("split"); strings.HasPrefix(split, "config.") {
+		if dpi, prs := densityQualifiers[split[len("config."):]]; prs {
+			return []int{dpi}, nil
+		}
+	}
+
+	if a.resources == nil {
+		if a.resourcesErr == os.ErrNotExist {
+			return nil, nil
+		}
+		return nil, a.resourcesErr
+	}
+
+	seen := make(map[int]bool)
+	var res []int
+	for _, group := range a.resources.packages {
+		for _, typeList := range group.types {
+			for _, typ := range typeList {
+				for _, cfg := range typ.Configs {
+					dpi := int(cfg.config.Density)
+					if dpi == 0 || dpi >= densityAny || seen[dpi] {
+						continue
+					}
+					seen[dpi] = true
+					res = append(res, dpi)
+				}
+			}
+		}
+	}
+	sort.Ints(res)
+	return res, nil
+}
+
+// Returns the attribute of the first manifest element with this name (e.g. "uses-sdk", "application")
+// which has it, as integer. Works for integer (decimal or hex) and boolean attributes, booleans are 1 or 0.
+//
+// Returns ErrNotFound if there is no such element or attribute.
+func (a *APK) AttributeValueAsInt(element, attrName string) (int32, error) {
+	val, err := a.AttributeValueAsString(element, attrName)
+	if err != nil {
+		return 0, err
+	}
+
+	switch val {
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(val, 0, 64)
+	if err != nil || v < -1<<31 || v > 1<<32-1 {
+		return 0, fmt.Errorf("Attribute %s of %s is not an integer: %s", attrName, element, val)
+	}
+	return int32(v), nil
+}
+
+// Returns the attribute of the first manifest element with this name which has it, as string.
+// Strings, booleans, hex integers and floats are returned as the manifest parser formats them.
+// References are resolved through resources.arsc, unresolvable ones are returned as "@" and
+// the hex resource id. Values of other types, like enums, dimensions or colors, are returned
+// as the raw data in hex, e.g. "0x00000002", use AttributeValueAsInt to get them as numbers.
+//
+// Returns ErrNotFound if there is no such element or attribute.
+func (a *APK) AttributeValueAsString(element, attrName string) (string, error) {
+	for _, el := range a.manifest.findAll(element) {
+		for i := range el.Attrs {
+			if el.Attrs[i].Name.Local != attrName {
+				continue
+			}
+
+			// Trees unmarshaled from data without the raw values only have the formatted ones.
+			if el.attrTypes == nil {
+				return el.Attrs[i].Value, nil
+			}
+
+			switch el.attrTypes[i] {
+			case AttrTypeString, AttrTypeIntBool, AttrTypeIntHex, AttrTypeFloat, AttrTypeReference:
+				return el.Attrs[i].Value, nil
+			default:
+				return fmt.Sprintf("0x%08x", el.attrData[i]), nil
+			}
+		}
+	}
+	return "", ErrNotFound
+}
@@ -0,0 +1,323 @@
+package apkparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+const testAndroidNs = "http://schemas.android.com/apk/res/android"
+
+// Attribute of testXmlElement, in the android namespace. String values are in str.
+type testXmlAttr struct {
+	name     string
+	dataType uint8
+	data     uint32
+	str      string
+}
+
+type testXmlElement struct {
+	name     string
+	attrs    []testXmlAttr
+	children []*testXmlElement
+}
+
+func testStringAttr(name, val string) testXmlAttr {
+	return testXmlAttr{name: name, dataType: AttrTypeString, str: val}
+}
+
+// Builds binary XML like aapt does, but without the resource ids chunk, so ParseManifest
+// takes the attribute names from the string pool.
+func buildAxml(root *testXmlElement) []byte {
+	le := binary.LittleEndian
+	strs := []string{"android", testAndroidNs}
+	strIdx := map[string]uint32{"android": 0, testAndroidNs: 1}
+	idx := func(s string) uint32 {
+		if i, prs := strIdx[s]; prs {
+			return i
+		}
+		strIdx[s] = uint32(len(strs))
+		strs = append(strs, s)
+		return strIdx[s]
+	}
+
+	var body bytes.Buffer
+	node := func(id uint16, size uint32) {
+		binary.Write(&body, le, id)
+		binary.Write(&body, le, uint16(16))
+		binary.Write(&body, le, size)
+		binary.Write(&body, le, uint32(1))          // line number
+		binary.Write(&body, le, uint32(0xffffffff)) // comment
+	}
+
+	node(chunkXmlNsStart, 24)
+	binary.Write(&body, le, [2]uint32{0, 1})
+
+	var write func(el *testXmlElement)
+	write = func(el *testXmlElement) {
+		node(chunkXmlTagStart, 16+20+20*uint32(len(el.attrs)))
+		binary.Write(&body, le, [2]uint32{0xffffffff, idx(el.name)})
+		binary.Write(&body, le, [2]uint16{20, 20}) // attribute start and size
+		binary.Write(&body, le, [4]uint16{uint16(len(el.attrs)), 0, 0, 0})
+		for _, attr := range el.attrs {
+			raw, data := uint32(0xffffffff), attr.data
+			if attr.dataType == AttrTypeString {
+				raw = idx(attr.str)
+				data = raw
+			}
+			binary.Write(&body, le, [3]uint32{1, idx(attr.name), raw})
+			binary.Write(&body, le, uint16(8))
+			binary.Write(&body, le, [2]uint8{0, attr.dataType})
+			binary.Write(&body, le, data)
+		}
+
+		for _, c := range el.children {
+			write(c)
+		}
+
+		node(chunkXmlTagEnd, 24)
+		binary.Write(&body, le, [2]uint32{0xffffffff, idx(el.name)})
+	}
+	write(root)
+
+	node(chunkXmlNsEnd, 24)
+	binary.Write(&body, le, [2]uint32{0, 1})
+
+	pool := buildStringPool(strs)
+
+	var out bytes.Buffer
+	binary.Write(&out, le, uint16(chunkAxmlFile))
+	binary.Write(&out, le, uint16(chunkHeaderSize))
+	binary.Write(&out, le, uint32(chunkHeaderSize+len(pool)+body.Len()))
+	out.Write(pool)
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// Writes the files into a zip in dir, sorted by name, returns its path.
+func writeTestAPK(t *testing.T, dir string, name string, files map[string][]byte) string {
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(files[name])
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testManifest() []byte {
+	return buildAxml(&testXmlElement{
+		name:  "manifest",
+		attrs: []testXmlAttr{testStringAttr("package", "com.example"), {name: "versionCode", dataType: AttrTypeIntDec, data: 42}},
+		children: []*testXmlElement{
+			{name: "uses-sdk", attrs: []testXmlAttr{{name: "minSdkVersion", dataType: AttrTypeIntDec, data: 21}, {name: "targetSdkVersion", dataType: AttrTypeIntDec, data: 33}}},
+			{name: "application", children: []*testXmlElement{
+				{name: "activity", attrs: []testXmlAttr{testStringAttr("name", ".Main"), {name: "launchMode", dataType: AttrTypeIntDec, data: 2}}},
+				{name: "activity", attrs: []testXmlAttr{testStringAttr("name", "com.example.Other")}},
+			}},
+		},
+	})
+}
+
+func openTestAPK(t *testing.T, files map[string][]byte) *APK {
+	a, err := OpenAPK(writeTestAPK(t, t.TempDir(), "test.apk", files))
+	if err != nil {
+		t.Fatalf("Failed to open the APK: %s", err.Error())
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestZeroAPK(t *testing.T) {
+	var a APK
+	if _, err := a.Clone(); err == nil {
+		t.Error("Clone: expected an error")
+	}
+	if _, err := a.MarshalJSON(); err == nil {
+		t.Error("MarshalJSON: expected an error")
+	}
+	if _, err := a.MarshalProto(); err == nil {
+		t.Error("MarshalProto: expected an error")
+	}
+}
+
+var testLaunchModes = map[string]LaunchMode{
+	"com.example.Main":  LaunchModeSingleTask,
+	"com.example.Other": LaunchModeStandard,
+}
+
+// Checks the AttributeValueAsString results of testManifest, which depend on the raw values.
+func checkTestManifestAttrs(t *testing.T, a *APK) {
+	for _, e := range []struct{ element, attr, expected string }{
+		{"manifest", "package", "com.example"},
+		{"manifest", "versionCode", "0x0000002a"},
+		{"activity", "launchMode", "0x00000002"},
+	} {
+		if str, err := a.AttributeValueAsString(e.element, e.attr); str != e.expected || err != nil {
+			t.Errorf("%s %s: got '%s' %v, expected '%s'", e.element, e.attr, str, err, e.expected)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	a := openTestAPK(t, map[string][]byte{
+		"AndroidManifest.xml": testManifest(),
+		"resources.arsc": buildArsc([]string{"Hello %s"}, "com.example", []testResType{{name: "string", configs: []testResConfig{
+			{entries: []*testResEntry{{key: "hello", dataType: AttrTypeString, data: 0}}},
+		}}}),
+	})
+
+	data, err := a.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %s", err.Error())
+	}
+
+	var b APK
+	if err := b.UnmarshalJSON(data); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %s", err.Error())
+	}
+
+	modes, err := b.LaunchMode()
+	if err != nil || !reflect.DeepEqual(modes, testLaunchModes) {
+		t.Errorf("LaunchMode: %v %v", modes, err)
+	}
+	checkTestManifestAttrs(t, &b)
+
+	if str, err := b.FormattedString("hello", language.Und, "world"); str != "Hello world" {
+		t.Errorf("FormattedString: '%s' %v", str, err)
+	}
+
+	again, err := b.MarshalJSON()
+	if err != nil || !bytes.Equal(data, again) {
+		t.Errorf("Second MarshalJSON differs: %v", err)
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	a := openTestAPK(t, map[string][]byte{"AndroidManifest.xml": testManifest()})
+
+	data, err := a.MarshalProto()
+	if err != nil {
+		t.Fatalf("MarshalProto failed: %s", err.Error())
+	}
+
+	var b APK
+	if err := b.UnmarshalProto(data); err != nil {
+		t.Fatalf("UnmarshalProto failed: %s", err.Error())
+	}
+
+	modes, err := b.LaunchMode()
+	if err != nil || !reflect.DeepEqual(modes, testLaunchModes) {
+		t.Errorf("LaunchMode: %v %v", modes, err)
+	}
+	checkTestManifestAttrs(t, &b)
+	if err := b.ResourcesError(); err != os.ErrNotExist {
+		t.Errorf("ResourcesError: %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	a := openTestAPK(t, map[string][]byte{"AndroidManifest.xml": testManifest()})
+
+	c, err := a.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %s", err.Error())
+	}
+
+	modes, err := c.LaunchMode()
+	if err != nil || !reflect.DeepEqual(modes, testLaunchModes) {
+		t.Errorf("LaunchMode: %v %v", modes, err)
+	}
+
+	c.manifest.Attrs[0].Value = "changed"
+	if a.packageName() != "com.example" {
+		t.Errorf("Clone shares the manifest, package is %s", a.packageName())
+	}
+}
+
+func TestAttributeValueAsString(t *testing.T) {
+	manifest := buildAxml(&testXmlElement{
+		name: "manifest",
+		attrs: []testXmlAttr{
+			testStringAttr("package", "com.example"),
+			{name: "installLocation", dataType: AttrTypeIntDec, data: 2},
+			{name: "versionCode", dataType: AttrTypeIntDec, data: 0xffffffff},
+		},
+		children: []*testXmlElement{
+			{name: "application", attrs: []testXmlAttr{
+				{name: "debuggable", dataType: AttrTypeIntBool, data: 0xffffffff},
+				{name: "uiOptions", dataType: AttrTypeIntHex, data: 0x1},
+				{name: "icon", dataType: AttrTypeReference, data: 0x7f020001},
+			}},
+			{name: "activity", attrs: []testXmlAttr{
+				{name: "minWidth", dataType: AttrTypeDimension, data: 0x3001},
+				{name: "windowBackground", dataType: AttrTypeIntColorArgb8, data: 0xff00ff00},
+			}},
+		},
+	})
+
+	expected := []struct {
+		element, attr string
+		str           string
+		num           int32
+	}{
+		{"manifest", "package", "com.example", 0},
+		{"manifest", "installLocation", "0x00000002", 2},
+		{"manifest", "versionCode", "0xffffffff", -1},
+		{"application", "debuggable", "true", 1},
+		{"application", "uiOptions", "0x1", 1},
+		{"application", "icon", "@7f020001", 0},
+		{"activity", "minWidth", "0x00003001", 0x3001},
+		{"activity", "windowBackground", "0xff00ff00", -0x1000000 + 0xff00},
+	}
+
+	a := openTestAPK(t, map[string][]byte{"AndroidManifest.xml": manifest})
+	for _, e := range expected {
+		if str, err := a.AttributeValueAsString(e.element, e.attr); str != e.str || err != nil {
+			t.Errorf("%s %s: got '%s' %v, expected '%s'", e.element, e.attr, str, err, e.str)
+		}
+		if e.num != 0 {
+			if num, err := a.AttributeValueAsInt(e.element, e.attr); num != e.num || err != nil {
+				t.Errorf("%s %s: got %d %v, expected %d", e.element, e.attr, num, err, e.num)
+			}
+		}
+	}
+
+	if _, err := a.AttributeValueAsString("manifest", "missing"); err != ErrNotFound {
+		t.Errorf("Missing attribute: %v", err)
+	}
+
+	// Cloned trees keep the raw values.
+	c, err := a.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str, _ := c.AttributeValueAsString("manifest", "installLocation"); str != "0x00000002" {
+		t.Errorf("Clone installLocation: '%s'", str)
+	}
+}
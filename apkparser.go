@@ -3,6 +3,7 @@ package apkparser
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime/debug"
 )
@@ -13,6 +14,9 @@ type apkParser struct {
 
 	encoder   ManifestEncoder
 	resources *ResourceTable
+
+	// Wraps readers of the zip files, may be nil.
+	wrapReader func(io.Reader) io.Reader
 }
 
 // Parse APK's Manifest, including resolving refences to resource values.
@@ -69,7 +73,12 @@ func (p *apkParser) parseResources() (err error) {
 	}
 	defer resourcesFile.Close()
 
-	p.resources, err = ParseResourceTable(resourcesFile)
+	var r io.Reader = resourcesFile
+	if p.wrapReader != nil {
+		r = p.wrapReader(r)
+	}
+
+	p.resources, err = ParseResourceTable(r)
 	return
 }
 
@@ -0,0 +1,131 @@
+// This is a tool to print an overview of an APK, see APK.StructuredLog.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/avast/apkparser"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var sections = []string{"manifest", "resources", "security", "size"}
+
+func main() {
+	format := flag.String("format", "text", "Output format: json, text or proto (APKSummary from apkpb/apk.proto, can't be used with -only)")
+	only := flag.String("only", "", "Comma separated sections to print: "+strings.Join(sections, ", ")+" (default all)")
+
+	flag.Parse()
+
+	if len(flag.Args()) != 1 {
+		fmt.Fprintf(os.Stderr, "%s [-format=json|text|proto] [-only=SECTIONS] APK\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if err := run(flag.Arg(0), *format, *only); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(path, format, only string) error {
+	switch format {
+	case "json", "text":
+	case "proto":
+		// The proto is a fixed APKSummary message, not the sections of StructuredLog.
+		if only != "" {
+			return fmt.Errorf("Option -only can't be used with -format=proto")
+		}
+	default:
+		return fmt.Errorf("Unknown format '%s', valid are: json, text, proto", format)
+	}
+
+	apk, err := apkparser.OpenAPK(path)
+	if err != nil {
+		return err
+	}
+	defer apk.Close()
+
+	if format == "proto" {
+		data, err := apk.MarshalProto()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	log, err := apk.StructuredLog()
+	if err != nil {
+		return err
+	}
+
+	if only != "" {
+		filtered := make(map[string]interface{})
+		for _, name := range strings.Split(only, ",") {
+			name = strings.TrimSpace(name)
+			val, prs := log[name]
+			if !prs {
+				return fmt.Errorf("Unknown section '%s', valid are: %s", name, strings.Join(sections, ", "))
+			}
+			filtered[name] = val
+		}
+		log = filtered
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(log)
+	}
+
+	printText("", reflect.ValueOf(log))
+	return nil
+}
+
+// Prints the value as "prefix: value" lines, nested maps have their keys joined by dots.
+func printText(prefix string, v reflect.Value) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		fmt.Printf("%s:\n", prefix)
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, k := range keys {
+			name := fmt.Sprint(k.Interface())
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			printText(name, v.MapIndex(k))
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			fmt.Printf("%s:\n", prefix)
+		}
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			for item.Kind() == reflect.Interface {
+				item = item.Elem()
+			}
+
+			if item.Kind() == reflect.Map {
+				printText(fmt.Sprintf("%s[%d]", prefix, i), item)
+			} else {
+				fmt.Printf("%s: %v\n", prefix, item.Interface())
+			}
+		}
+	default:
+		fmt.Printf("%s: %v\n", prefix, v.Interface())
+	}
+}
@@ -0,0 +1,577 @@
+// Wire format of APK.MarshalProto. Regenerate apk.pb.go after changing it:
+//
+//     protoc --go_out=. --go_opt=paths=source_relative apk.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: apk.proto
+
+package apkpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type APKSummary struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	PackageName      string                 `protobuf:"bytes,1,opt,name=package_name,json=packageName,proto3" json:"package_name,omitempty"`
+	VersionCode      int32                  `protobuf:"varint,2,opt,name=version_code,json=versionCode,proto3" json:"version_code,omitempty"`
+	VersionName      string                 `protobuf:"bytes,3,opt,name=version_name,json=versionName,proto3" json:"version_name,omitempty"`
+	MinSdkVersion    int32                  `protobuf:"varint,4,opt,name=min_sdk_version,json=minSdkVersion,proto3" json:"min_sdk_version,omitempty"`
+	TargetSdkVersion int32                  `protobuf:"varint,5,opt,name=target_sdk_version,json=targetSdkVersion,proto3" json:"target_sdk_version,omitempty"`
+	Manifest         *Manifest              `protobuf:"bytes,6,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	Resources        *ResourceTableSummary  `protobuf:"bytes,7,opt,name=resources,proto3" json:"resources,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *APKSummary) Reset() {
+	*x = APKSummary{}
+	mi := &file_apk_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *APKSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*APKSummary) ProtoMessage() {}
+
+func (x *APKSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use APKSummary.ProtoReflect.Descriptor instead.
+func (*APKSummary) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *APKSummary) GetPackageName() string {
+	if x != nil {
+		return x.PackageName
+	}
+	return ""
+}
+
+func (x *APKSummary) GetVersionCode() int32 {
+	if x != nil {
+		return x.VersionCode
+	}
+	return 0
+}
+
+func (x *APKSummary) GetVersionName() string {
+	if x != nil {
+		return x.VersionName
+	}
+	return ""
+}
+
+func (x *APKSummary) GetMinSdkVersion() int32 {
+	if x != nil {
+		return x.MinSdkVersion
+	}
+	return 0
+}
+
+func (x *APKSummary) GetTargetSdkVersion() int32 {
+	if x != nil {
+		return x.TargetSdkVersion
+	}
+	return 0
+}
+
+func (x *APKSummary) GetManifest() *Manifest {
+	if x != nil {
+		return x.Manifest
+	}
+	return nil
+}
+
+func (x *APKSummary) GetResources() *ResourceTableSummary {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+type Manifest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Root          *Element               `protobuf:"bytes,1,opt,name=root,proto3" json:"root,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Manifest) Reset() {
+	*x = Manifest{}
+	mi := &file_apk_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Manifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Manifest) ProtoMessage() {}
+
+func (x *Manifest) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Manifest.ProtoReflect.Descriptor instead.
+func (*Manifest) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Manifest) GetRoot() *Element {
+	if x != nil {
+		return x.Root
+	}
+	return nil
+}
+
+type Element struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Attributes    []*Attribute           `protobuf:"bytes,2,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	Children      []*Element             `protobuf:"bytes,3,rep,name=children,proto3" json:"children,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Element) Reset() {
+	*x = Element{}
+	mi := &file_apk_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Element) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Element) ProtoMessage() {}
+
+func (x *Element) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Element.ProtoReflect.Descriptor instead.
+func (*Element) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Element) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Element) GetAttributes() []*Attribute {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *Element) GetChildren() []*Element {
+	if x != nil {
+		return x.Children
+	}
+	return nil
+}
+
+func (x *Element) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type Attribute struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name      string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Value     string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// Res_value type and data from the binary XML, not set for attributes of manifests which weren't
+	// parsed from it.
+	Type          *uint32 `protobuf:"varint,4,opt,name=type,proto3,oneof" json:"type,omitempty"`
+	Data          uint32  `protobuf:"varint,5,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attribute) Reset() {
+	*x = Attribute{}
+	mi := &file_apk_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attribute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attribute) ProtoMessage() {}
+
+func (x *Attribute) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attribute.ProtoReflect.Descriptor instead.
+func (*Attribute) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Attribute) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Attribute) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Attribute) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Attribute) GetType() uint32 {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return 0
+}
+
+func (x *Attribute) GetData() uint32 {
+	if x != nil {
+		return x.Data
+	}
+	return 0
+}
+
+type ResourceTableSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Packages      []*ResourcePackage     `protobuf:"bytes,1,rep,name=packages,proto3" json:"packages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceTableSummary) Reset() {
+	*x = ResourceTableSummary{}
+	mi := &file_apk_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceTableSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceTableSummary) ProtoMessage() {}
+
+func (x *ResourceTableSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceTableSummary.ProtoReflect.Descriptor instead.
+func (*ResourceTableSummary) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ResourceTableSummary) GetPackages() []*ResourcePackage {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+type ResourcePackage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Types         []*ResourceType        `protobuf:"bytes,3,rep,name=types,proto3" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourcePackage) Reset() {
+	*x = ResourcePackage{}
+	mi := &file_apk_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourcePackage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourcePackage) ProtoMessage() {}
+
+func (x *ResourcePackage) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourcePackage.ProtoReflect.Descriptor instead.
+func (*ResourcePackage) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResourcePackage) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ResourcePackage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ResourcePackage) GetTypes() []*ResourceType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type ResourceType struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// e.g. "string", "drawable"
+	Name          string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	EntryCount    uint32 `protobuf:"varint,2,opt,name=entry_count,json=entryCount,proto3" json:"entry_count,omitempty"`
+	ConfigCount   uint32 `protobuf:"varint,3,opt,name=config_count,json=configCount,proto3" json:"config_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceType) Reset() {
+	*x = ResourceType{}
+	mi := &file_apk_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceType) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceType) ProtoMessage() {}
+
+func (x *ResourceType) ProtoReflect() protoreflect.Message {
+	mi := &file_apk_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceType.ProtoReflect.Descriptor instead.
+func (*ResourceType) Descriptor() ([]byte, []int) {
+	return file_apk_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResourceType) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ResourceType) GetEntryCount() uint32 {
+	if x != nil {
+		return x.EntryCount
+	}
+	return 0
+}
+
+func (x *ResourceType) GetConfigCount() uint32 {
+	if x != nil {
+		return x.ConfigCount
+	}
+	return 0
+}
+
+var File_apk_proto protoreflect.FileDescriptor
+
+const file_apk_proto_rawDesc = "" +
+	"\n" +
+	"\tapk.proto\x12\tapkparser\"\xbb\x02\n" +
+	"\n" +
+	"APKSummary\x12!\n" +
+	"\fpackage_name\x18\x01 \x01(\tR\vpackageName\x12!\n" +
+	"\fversion_code\x18\x02 \x01(\x05R\vversionCode\x12!\n" +
+	"\fversion_name\x18\x03 \x01(\tR\vversionName\x12&\n" +
+	"\x0fmin_sdk_version\x18\x04 \x01(\x05R\rminSdkVersion\x12,\n" +
+	"\x12target_sdk_version\x18\x05 \x01(\x05R\x10targetSdkVersion\x12/\n" +
+	"\bmanifest\x18\x06 \x01(\v2\x13.apkparser.ManifestR\bmanifest\x12=\n" +
+	"\tresources\x18\a \x01(\v2\x1f.apkparser.ResourceTableSummaryR\tresources\"2\n" +
+	"\bManifest\x12&\n" +
+	"\x04root\x18\x01 \x01(\v2\x12.apkparser.ElementR\x04root\"\x97\x01\n" +
+	"\aElement\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x124\n" +
+	"\n" +
+	"attributes\x18\x02 \x03(\v2\x14.apkparser.AttributeR\n" +
+	"attributes\x12.\n" +
+	"\bchildren\x18\x03 \x03(\v2\x12.apkparser.ElementR\bchildren\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\"\x89\x01\n" +
+	"\tAttribute\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x17\n" +
+	"\x04type\x18\x04 \x01(\rH\x00R\x04type\x88\x01\x01\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\rR\x04dataB\a\n" +
+	"\x05_type\"N\n" +
+	"\x14ResourceTableSummary\x126\n" +
+	"\bpackages\x18\x01 \x03(\v2\x1a.apkparser.ResourcePackageR\bpackages\"d\n" +
+	"\x0fResourcePackage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12-\n" +
+	"\x05types\x18\x03 \x03(\v2\x17.apkparser.ResourceTypeR\x05types\"f\n" +
+	"\fResourceType\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1f\n" +
+	"\ventry_count\x18\x02 \x01(\rR\n" +
+	"entryCount\x12!\n" +
+	"\fconfig_count\x18\x03 \x01(\rR\vconfigCountB\"Z github.com/avast/apkparser/apkpbb\x06proto3"
+
+var (
+	file_apk_proto_rawDescOnce sync.Once
+	file_apk_proto_rawDescData []byte
+)
+
+func file_apk_proto_rawDescGZIP() []byte {
+	file_apk_proto_rawDescOnce.Do(func() {
+		file_apk_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_apk_proto_rawDesc), len(file_apk_proto_rawDesc)))
+	})
+	return file_apk_proto_rawDescData
+}
+
+var file_apk_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_apk_proto_goTypes = []any{
+	(*APKSummary)(nil),           // 0: apkparser.APKSummary
+	(*Manifest)(nil),             // 1: apkparser.Manifest
+	(*Element)(nil),              // 2: apkparser.Element
+	(*Attribute)(nil),            // 3: apkparser.Attribute
+	(*ResourceTableSummary)(nil), // 4: apkparser.ResourceTableSummary
+	(*ResourcePackage)(nil),      // 5: apkparser.ResourcePackage
+	(*ResourceType)(nil),         // 6: apkparser.ResourceType
+}
+var file_apk_proto_depIdxs = []int32{
+	1, // 0: apkparser.APKSummary.manifest:type_name -> apkparser.Manifest
+	4, // 1: apkparser.APKSummary.resources:type_name -> apkparser.ResourceTableSummary
+	2, // 2: apkparser.Manifest.root:type_name -> apkparser.Element
+	3, // 3: apkparser.Element.attributes:type_name -> apkparser.Attribute
+	2, // 4: apkparser.Element.children:type_name -> apkparser.Element
+	5, // 5: apkparser.ResourceTableSummary.packages:type_name -> apkparser.ResourcePackage
+	6, // 6: apkparser.ResourcePackage.types:type_name -> apkparser.ResourceType
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_apk_proto_init() }
+func file_apk_proto_init() {
+	if File_apk_proto != nil {
+		return
+	}
+	file_apk_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_apk_proto_rawDesc), len(file_apk_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_apk_proto_goTypes,
+		DependencyIndexes: file_apk_proto_depIdxs,
+		MessageInfos:      file_apk_proto_msgTypes,
+	}.Build()
+	File_apk_proto = out.File
+	file_apk_proto_goTypes = nil
+	file_apk_proto_depIdxs = nil
+}
@@ -0,0 +1,143 @@
+package apkparser
+
+// Returns the class name of the custom backup agent from android:backupAgent in <application>,
+// or "" if the app uses the default one.
+func (a *APK) BackupAgent() (string, error) {
+	app := a.manifest.child("application")
+	if app == nil {
+		return "", nil
+	}
+	return a.className(app.attrOrEmpty("backupAgent")), nil
+}
+
+// Returns the value of boolean attribute of <application>, def if it is not set.
+func (a *APK) applicationBoolAttr(name string, def bool) bool {
+	app := a.manifest.child("application")
+	if app == nil {
+		return def
+	}
+
+	if val, prs := app.attr(name); prs {
+		return val == "true"
+	}
+	return def
+}
+
+// Returns android:allowBackup of <application>, which defaults to true.
+//
+// Note that on Android 12+, allowBackup="false" doesn't stop device-to-device transfers of apps
+// targeting SDK 31+ which have android:dataExtractionRules, only the rules XML can exclude data
+// from them.
+func (a *APK) AllowBackup() (bool, error) {
+	return a.applicationBoolAttr("allowBackup", true), nil
+}
+
+// Returns android:fullBackupOnly of <application>. When true, apps with a backupAgent use Auto Backup
+// of whole files on SDK 23+ instead of the key/value backup.
+func (a *APK) FullBackupOnly() (bool, error) {
+	return a.applicationBoolAttr("fullBackupOnly", false), nil
+}
+
+// Returns android:persistent of <application>. Android only honors it for system apps, which are then
+// kept running and restarted when killed. Together with android:sharedUserId it points to a system component.
+func (a *APK) PersistentApp() (bool, error) {
+	return a.applicationBoolAttr("persistent", false), nil
+}
+
+// Returns android:vmSafeMode of <application>, which disables JIT (and AOT compilation on ART) for the app.
+func (a *APK) VMSafeMode() (bool, error) {
+	return a.applicationBoolAttr("vmSafeMode", false), nil
+}
+
+// Flags of ApplicationInfo.flags, as PackageManager sets them from the manifest.
+const (
+	AppFlagDebuggable              = 1 << 1
+	AppFlagHasCode                 = 1 << 2
+	AppFlagPersistent              = 1 << 3
+	AppFlagAllowTaskReparenting    = 1 << 5
+	AppFlagAllowClearUserData      = 1 << 6
+	AppFlagTestOnly                = 1 << 8
+	AppFlagSupportsSmallScreens    = 1 << 9
+	AppFlagSupportsNormalScreens   = 1 << 10
+	AppFlagSupportsLargeScreens    = 1 << 11
+	AppFlagResizeableForScreens    = 1 << 12
+	AppFlagSupportsScreenDensities = 1 << 13
+	AppFlagVMSafeMode              = 1 << 14
+	AppFlagAllowBackup             = 1 << 15
+	AppFlagKillAfterRestore        = 1 << 16
+	AppFlagRestoreAnyVersion       = 1 << 17
+	AppFlagSupportsXLargeScreens   = 1 << 19
+	AppFlagLargeHeap               = 1 << 20
+	AppFlagSupportsRtl             = 1 << 22
+	AppFlagIsGame                  = 1 << 25
+	AppFlagFullBackupOnly          = 1 << 26
+	AppFlagUsesCleartextTraffic    = 1 << 27
+	AppFlagExtractNativeLibs       = 1 << 28
+	AppFlagHardwareAccelerated     = 1 << 29
+	AppFlagMultiArch               = 1 << 31
+)
+
+// Returns ApplicationInfo.flags (the AppFlag* constants) computed from the manifest the way
+// PackageParser does it, including the attribute defaults which depend on targetSdkVersion.
+// Flags which describe the installed state, like FLAG_SYSTEM or FLAG_STOPPED, are never set.
+func (a *APK) PackageManagerFlags() (uint32, error) {
+	targetSdk := a.targetSdkVersion()
+	var res uint32
+
+	set := func(flag uint32, name string, def bool) {
+		if a.applicationBoolAttr(name, def) {
+			res |= flag
+		}
+	}
+
+	set(AppFlagDebuggable, "debuggable", false)
+	set(AppFlagHasCode, "hasCode", true)
+	set(AppFlagPersistent, "persistent", false)
+	set(AppFlagAllowTaskReparenting, "allowTaskReparenting", false)
+	set(AppFlagAllowClearUserData, "allowClearUserData", true)
+	set(AppFlagTestOnly, "testOnly", false)
+	set(AppFlagVMSafeMode, "vmSafeMode", false)
+	set(AppFlagLargeHeap, "largeHeap", false)
+	set(AppFlagSupportsRtl, "supportsRtl", false)
+	set(AppFlagIsGame, "isGame", false)
+	set(AppFlagUsesCleartextTraffic, "usesCleartextTraffic", targetSdk < 28)
+	set(AppFlagExtractNativeLibs, "extractNativeLibs", true)
+	set(AppFlagHardwareAccelerated, "hardwareAccelerated", targetSdk >= 14)
+	set(AppFlagMultiArch, "multiArch", false)
+
+	if a.applicationBoolAttr("allowBackup", true) {
+		res |= AppFlagAllowBackup
+
+		// Only read when the app has its own backup agent.
+		if app := a.manifest.child("application"); app != nil && app.attrOrEmpty("backupAgent") != "" {
+			set(AppFlagKillAfterRestore, "killAfterRestore", true)
+			set(AppFlagRestoreAnyVersion, "restoreAnyVersion", false)
+			set(AppFlagFullBackupOnly, "fullBackupOnly", false)
+		}
+	}
+
+	// Explicit true always sets the flag, but the implicit defaults only apply to apps
+	// new enough to know about the screen size.
+	supportsScreens := a.manifest.child("supports-screens")
+	screens := func(flag uint32, name string, minSdk int) {
+		if supportsScreens != nil {
+			if val, prs := supportsScreens.attr(name); prs {
+				if val == "true" {
+					res |= flag
+				}
+				return
+			}
+		}
+		if targetSdk >= minSdk {
+			res |= flag
+		}
+	}
+
+	screens(AppFlagSupportsSmallScreens, "smallScreens", 4)
+	screens(AppFlagSupportsNormalScreens, "normalScreens", 0)
+	screens(AppFlagSupportsLargeScreens, "largeScreens", 4)
+	screens(AppFlagSupportsXLargeScreens, "xlargeScreens", 9)
+	screens(AppFlagResizeableForScreens, "resizeable", 4)
+	screens(AppFlagSupportsScreenDensities, "anyDensity", 4)
+	return res, nil
+}
@@ -0,0 +1,90 @@
+package apkparser
+
+import (
+	"testing"
+)
+
+func testBoolAttr(name string, val bool) testXmlAttr {
+	attr := testXmlAttr{name: name, dataType: AttrTypeIntBool}
+	if val {
+		attr.data = 0xffffffff
+	}
+	return attr
+}
+
+func TestPackageManagerFlags(t *testing.T) {
+	const defaults = AppFlagHasCode | AppFlagAllowClearUserData | AppFlagExtractNativeLibs | AppFlagAllowBackup |
+		AppFlagSupportsSmallScreens | AppFlagSupportsNormalScreens | AppFlagSupportsLargeScreens |
+		AppFlagSupportsXLargeScreens | AppFlagResizeableForScreens | AppFlagSupportsScreenDensities
+
+	tests := []struct {
+		name      string
+		targetSdk uint32
+		app       []testXmlAttr
+		screens   []testXmlAttr
+		expected  uint32
+	}{
+		{"defaults", 33, nil, nil, defaults | AppFlagHardwareAccelerated},
+		{"old sdk", 3, nil, nil, AppFlagHasCode | AppFlagAllowClearUserData | AppFlagExtractNativeLibs |
+			AppFlagAllowBackup | AppFlagSupportsNormalScreens | AppFlagUsesCleartextTraffic},
+		{"attributes", 33, []testXmlAttr{testBoolAttr("debuggable", true), testBoolAttr("hasCode", false),
+			testBoolAttr("hardwareAccelerated", false), testBoolAttr("multiArch", true)}, nil,
+			defaults&^AppFlagHasCode | AppFlagDebuggable | AppFlagMultiArch},
+		{"backup agent", 33, []testXmlAttr{testStringAttr("backupAgent", ".Agent"), testBoolAttr("fullBackupOnly", true)}, nil,
+			defaults | AppFlagHardwareAccelerated | AppFlagKillAfterRestore | AppFlagFullBackupOnly},
+		{"no backup", 33, []testXmlAttr{testBoolAttr("allowBackup", false), testStringAttr("backupAgent", ".Agent")}, nil,
+			defaults&^AppFlagAllowBackup | AppFlagHardwareAccelerated},
+		{"screens", 3, nil, []testXmlAttr{testBoolAttr("xlargeScreens", true), testBoolAttr("normalScreens", false)},
+			AppFlagHasCode | AppFlagAllowClearUserData | AppFlagExtractNativeLibs | AppFlagAllowBackup |
+				AppFlagSupportsXLargeScreens | AppFlagUsesCleartextTraffic},
+	}
+
+	for _, test := range tests {
+		manifest := &testXmlElement{
+			name:  "manifest",
+			attrs: []testXmlAttr{testStringAttr("package", "com.example")},
+			children: []*testXmlElement{
+				{name: "uses-sdk", attrs: []testXmlAttr{{name: "targetSdkVersion", dataType: AttrTypeIntDec, data: test.targetSdk}}},
+				{name: "application", attrs: test.app},
+			},
+		}
+		if test.screens != nil {
+			manifest.children = append(manifest.children, &testXmlElement{name: "supports-screens", attrs: test.screens})
+		}
+
+		a := openTestAPK(t, map[string][]byte{"AndroidManifest.xml": buildAxml(manifest)})
+		flags, err := a.PackageManagerFlags()
+		if err != nil || flags != test.expected {
+			t.Errorf("%s: got 0x%08x, expected 0x%08x (%v)", test.name, flags, test.expected, err)
+		}
+	}
+}
+
+func TestAllowBackup(t *testing.T) {
+	tests := []struct {
+		name      string
+		targetSdk uint32
+		app       []testXmlAttr
+		expected  bool
+	}{
+		{"default", 33, nil, true},
+		{"disabled", 30, []testXmlAttr{testBoolAttr("allowBackup", false)}, false},
+		{"disabled with rules", 33, []testXmlAttr{testBoolAttr("allowBackup", false), testStringAttr("dataExtractionRules", "@xml/rules")}, false},
+		{"enabled with rules", 33, []testXmlAttr{testBoolAttr("allowBackup", true), testStringAttr("dataExtractionRules", "@xml/rules")}, true},
+	}
+
+	for _, test := range tests {
+		a := openTestAPK(t, map[string][]byte{"AndroidManifest.xml": buildAxml(&testXmlElement{
+			name:  "manifest",
+			attrs: []testXmlAttr{testStringAttr("package", "com.example")},
+			children: []*testXmlElement{
+				{name: "uses-sdk", attrs: []testXmlAttr{{name: "targetSdkVersion", dataType: AttrTypeIntDec, data: test.targetSdk}}},
+				{name: "application", attrs: test.app},
+			},
+		})})
+
+		if res, err := a.AllowBackup(); res != test.expected || err != nil {
+			t.Errorf("%s: got %v %v", test.name, res, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package apkparser
+
+import (
+	"strings"
+)
+
+// Returns the Android Automotive OS permissions (android.car.permission.*, android.permission.CAR_* etc.)
+// the app requests with <uses-permission>.
+func (a *APK) AndroidAutoPermissions() ([]string, error) {
+	var res []string
+	for _, perm := range a.usesPermissions() {
+		if strings.HasPrefix(perm, "android.car.") ||
+			strings.HasPrefix(perm, "android.permission.CAR_") ||
+			strings.HasPrefix(perm, "com.google.android.gms.permission.CAR_") {
+			res = append(res, perm)
+		}
+	}
+	return res, nil
+}
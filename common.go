@@ -41,6 +41,8 @@ const (
 	AttrTypeAttribute              = 0x02
 	AttrTypeString                 = 0x03
 	AttrTypeFloat                  = 0x04
+	AttrTypeDimension              = 0x05
+	AttrTypeFraction               = 0x06
 	AttrTypeIntDec                 = 0x10
 	AttrTypeIntHex                 = 0x11
 	AttrTypeIntBool                = 0x12
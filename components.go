@@ -0,0 +1,356 @@
+package apkparser
+
+import (
+	"strings"
+)
+
+// Returns the <application> children with any of these element names, e.g. "activity", "service".
+// Returns all components if no names are passed.
+func (a *APK) components(names ...string) []*xmlElement {
+	app := a.manifest.child("application")
+	if app == nil {
+		return nil
+	}
+
+	if len(names) == 0 {
+		names = []string{"activity", "activity-alias", "service", "receiver", "provider"}
+	}
+
+	var res []*xmlElement
+	for _, c := range app.Children {
+		for _, n := range names {
+			if c.Name == n {
+				res = append(res, c)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// Returns a map from class name of each component with these element names to its boolean attribute,
+// def if it is not set.
+func (a *APK) componentsBoolAttr(attr string, def bool, names ...string) map[string]bool {
+	res := make(map[string]bool)
+	for _, c := range a.components(names...) {
+		val := def
+		if v, prs := c.attr(attr); prs {
+			val = v == "true"
+		}
+		res[a.componentName(c)] = val
+	}
+	return res
+}
+
+// Returns all android:scheme values from <data> elements of all intent filters, without duplicates.
+func (a *APK) IntentSchemes() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, filter := range a.manifest.findAll("intent-filter") {
+		for _, data := range filter.children("data") {
+			if scheme := data.attrOrEmpty("scheme"); scheme != "" && !seen[scheme] {
+				seen[scheme] = true
+				res = append(res, scheme)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Web link handled by an activity, see APK.AppLinks.
+type AppLink struct {
+	Host   string
+	Scheme string
+	// Verified Android App Link, the intent filter has android:autoVerify="true".
+	AutoVerify   bool
+	ActivityName string
+}
+
+// Returns the http and https links handled by activities, from intent filters with
+// the VIEW action and BROWSABLE category. The ones with AutoVerify set are Android App Links.
+func (a *APK) AppLinks() ([]AppLink, error) {
+	var res []AppLink
+	for _, act := range a.components("activity", "activity-alias") {
+		for _, filter := range act.children("intent-filter") {
+			if !filterHas(filter, "action", "android.intent.action.VIEW") ||
+				!filterHas(filter, "category", "android.intent.category.BROWSABLE") {
+				continue
+			}
+
+			// All <data> elements in one filter are merged, every scheme goes with every host.
+			var schemes, hosts []string
+			for _, data := range filter.children("data") {
+				if scheme := data.attrOrEmpty("scheme"); scheme == "http" || scheme == "https" {
+					schemes = append(schemes, scheme)
+				}
+				if host := data.attrOrEmpty("host"); host != "" {
+					hosts = append(hosts, host)
+				}
+			}
+
+			autoVerify := filter.attrOrEmpty("autoVerify") == "true"
+			for _, scheme := range schemes {
+				for _, host := range hosts {
+					res = append(res, AppLink{
+						Host:         host,
+						Scheme:       scheme,
+						AutoVerify:   autoVerify,
+						ActivityName: a.componentName(act),
+					})
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// Returns true if the intent filter has a child element (action, category) with this android:name.
+func filterHas(filter *xmlElement, element, name string) bool {
+	for _, el := range filter.children(element) {
+		if strings.TrimSpace(el.attrOrEmpty("name")) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Hosts and path parts typical for OAuth redirect URIs, like myapp://callback or com.example:/oauth2redirect.
+var oauthRedirectKeywords = []string{"callback", "oauth", "redirect"}
+
+// Returns schemes of intent filters which look like OAuth redirect URI receivers: their <data> host
+// or path contains "callback", "oauth" or "redirect", or the activity is AppAuth's RedirectUriReceiverActivity.
+func (a *APK) OAuthRedirectSchemes() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, act := range a.components("activity", "activity-alias") {
+		isAppAuth := a.componentName(act) == "net.openid.appauth.RedirectUriReceiverActivity"
+		for _, filter := range act.children("intent-filter") {
+			var schemes []string
+			redirect := isAppAuth
+			for _, data := range filter.children("data") {
+				if scheme := data.attrOrEmpty("scheme"); scheme != "" {
+					schemes = append(schemes, scheme)
+				}
+
+				for _, name := range []string{"host", "path", "pathPrefix", "pathPattern"} {
+					val := strings.ToLower(data.attrOrEmpty(name))
+					for _, kw := range oauthRedirectKeywords {
+						if strings.Contains(val, kw) {
+							redirect = true
+						}
+					}
+				}
+			}
+
+			if !redirect {
+				continue
+			}
+
+			for _, scheme := range schemes {
+				if !seen[scheme] {
+					seen[scheme] = true
+					res = append(res, scheme)
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// Returns custom URL schemes (all but http and https) from intent filters of activities and services,
+// mapped to the class name of the component which handles them. If more components handle
+// the same scheme, the first one in the manifest is returned.
+func (a *APK) CustomSchemeHandlers() (map[string]string, error) {
+	res := make(map[string]string)
+	for _, c := range a.components("activity", "activity-alias", "service") {
+		for _, filter := range c.children("intent-filter") {
+			for _, data := range filter.children("data") {
+				scheme := data.attrOrEmpty("scheme")
+				if scheme == "" || scheme == "http" || scheme == "https" {
+					continue
+				}
+
+				if _, prs := res[scheme]; !prs {
+					res[scheme] = a.componentName(c)
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// Broadcast receiver declared in the manifest, see APK.ReceiversWithDataSchemes.
+type Receiver struct {
+	Name       string
+	Exported   bool
+	Permission string
+	// Actions and data schemes of all its intent filters.
+	Actions []string
+	Schemes []string
+}
+
+// Returns receivers which have at least one <data android:scheme> in their intent filters,
+// so they get broadcasts for URIs, like ACTION_PACKAGE_ADDED with package: URIs.
+func (a *APK) ReceiversWithDataSchemes() ([]Receiver, error) {
+	var res []Receiver
+	for _, r := range a.components("receiver") {
+		rec := Receiver{
+			Name:       a.componentName(r),
+			Exported:   a.isExported(r),
+			Permission: a.componentPermission(r),
+		}
+
+		for _, filter := range r.children("intent-filter") {
+			for _, act := range filter.children("action") {
+				if name := act.attrOrEmpty("name"); name != "" {
+					rec.Actions = append(rec.Actions, name)
+				}
+			}
+			for _, data := range filter.children("data") {
+				if scheme := data.attrOrEmpty("scheme"); scheme != "" {
+					rec.Schemes = append(rec.Schemes, scheme)
+				}
+			}
+		}
+
+		if len(rec.Schemes) != 0 {
+			res = append(res, rec)
+		}
+	}
+	return res, nil
+}
+
+// Returns names of exported components with these element names which require no permission.
+func (a *APK) exportedWithoutPermission(names ...string) []string {
+	var res []string
+	for _, c := range a.components(names...) {
+		if a.isExported(c) && a.componentPermission(c) == "" {
+			res = append(res, a.componentName(c))
+		}
+	}
+	return res
+}
+
+// Returns names of exported activities (and aliases) which don't require any permission,
+// so any app can start them.
+func (a *APK) ExportedActivitiesWithoutPermission() ([]string, error) {
+	return a.exportedWithoutPermission("activity", "activity-alias"), nil
+}
+
+// Returns names of exported services which don't require any permission, so any app can
+// start or bind them.
+func (a *APK) ExportedServicesWithoutPermission() ([]string, error) {
+	return a.exportedWithoutPermission("service"), nil
+}
+
+// Broadcasts only the system can send, receivers of these can't be triggered by other apps
+// through the intent filter.
+var protectedBroadcasts = map[string]bool{
+	"android.intent.action.BOOT_COMPLETED":            true,
+	"android.intent.action.LOCKED_BOOT_COMPLETED":     true,
+	"android.intent.action.MY_PACKAGE_REPLACED":       true,
+	"android.intent.action.PACKAGE_ADDED":             true,
+	"android.intent.action.PACKAGE_REMOVED":           true,
+	"android.intent.action.PACKAGE_REPLACED":          true,
+	"android.intent.action.PACKAGE_FULLY_REMOVED":     true,
+	"android.intent.action.ACTION_POWER_CONNECTED":    true,
+	"android.intent.action.ACTION_POWER_DISCONNECTED": true,
+	"android.intent.action.ACTION_SHUTDOWN":           true,
+	"android.intent.action.REBOOT":                    true,
+	"android.intent.action.BATTERY_LOW":               true,
+	"android.intent.action.BATTERY_OKAY":              true,
+	"android.intent.action.DATE_CHANGED":              true,
+	"android.intent.action.TIME_SET":                  true,
+	"android.intent.action.TIMEZONE_CHANGED":          true,
+	"android.intent.action.LOCALE_CHANGED":            true,
+	"android.intent.action.USER_PRESENT":              true,
+	"android.intent.action.MEDIA_MOUNTED":             true,
+	"android.intent.action.MEDIA_UNMOUNTED":           true,
+	"android.net.conn.CONNECTIVITY_CHANGE":            true,
+	"android.app.action.DEVICE_ADMIN_ENABLED":         true,
+}
+
+// Returns names of exported receivers which don't require any permission, so any app can send
+// them broadcasts. Receivers whose intent filters only have system protected actions, like
+// BOOT_COMPLETED, are not included.
+func (a *APK) ExportedReceiversWithoutPermission() ([]string, error) {
+	var res []string
+	for _, r := range a.components("receiver") {
+		if !a.isExported(r) || a.componentPermission(r) != "" {
+			continue
+		}
+
+		filters := r.children("intent-filter")
+		protected := len(filters) != 0
+		for _, filter := range filters {
+			actions := filter.children("action")
+			if len(actions) == 0 {
+				protected = false
+			}
+			for _, act := range actions {
+				if !protectedBroadcasts[strings.TrimSpace(act.attrOrEmpty("name"))] {
+					protected = false
+				}
+			}
+		}
+
+		if !protected {
+			res = append(res, a.componentName(r))
+		}
+	}
+	return res, nil
+}
+
+// Returns a map from class name of every service to its android:stopWithTask. Services with false
+// (the default) keep running after the user swipes the app's task away.
+func (a *APK) StopWithTask() (map[string]bool, error) {
+	return a.componentsBoolAttr("stopWithTask", false, "service"), nil
+}
+
+// Returns a map from class name of every component to its android:directBootAware, true
+// for the ones which can run before the user unlocks the device after a reboot (SDK 24+).
+// Unset values are taken from <application>.
+func (a *APK) DirectBootAware() (map[string]bool, error) {
+	return a.componentsBoolAttr("directBootAware", a.applicationBoolAttr("directBootAware", false)), nil
+}
+
+// Returns the component (activity, activity-alias, service, receiver or provider) with this
+// class name, either fully qualified or as it is written in the manifest. Returns nil if there is none.
+func (a *APK) findComponent(name string) *xmlElement {
+	for _, c := range a.components() {
+		if a.componentName(c) == name || c.attrOrEmpty("name") == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Returns true if the component with this class name is enabled: both its android:enabled
+// and the one of <application>, which default to true, are not "false".
+//
+// Returns ErrNotFound if there is no such component.
+func (a *APK) ComponentEnabled(name string) (bool, error) {
+	c := a.findComponent(name)
+	if c == nil {
+		return false, ErrNotFound
+	}
+	return a.applicationBoolAttr("enabled", true) && c.attrOrEmpty("enabled") != "false", nil
+}
+
+// Returns the permission other apps need to use the component with this class name, from its
+// android:permission or the one of <application>. Providers return their read permission.
+// Returns "" if no permission is required.
+//
+// Returns ErrNotFound if there is no such component.
+func (a *APK) PermissionRequired(componentName string) (string, error) {
+	c := a.findComponent(componentName)
+	if c == nil {
+		return "", ErrNotFound
+	}
+
+	if c.Name == "provider" {
+		read, _ := readWritePermissions(c, a.componentPermission(c))
+		return read, nil
+	}
+	return a.componentPermission(c), nil
+}
@@ -0,0 +1,103 @@
+package apkparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Returns all <dist:conditions> elements of the dynamic feature module in this APK.
+func (a *APK) distConditions() []*xmlElement {
+	var res []*xmlElement
+	for _, module := range a.manifest.children("module") {
+		res = append(res, module.findAll("conditions")...)
+	}
+	return res
+}
+
+// Returns the min and max SDK version this dynamic feature module is delivered to,
+// from its <dist:min-sdk> and <dist:max-sdk> delivery conditions.
+// Either value is -1 if it is not set.
+func (a *APK) SdkTargeting() (min, max int, err error) {
+	min, max = -1, -1
+	for _, cond := range a.distConditions() {
+		for _, c := range cond.Children {
+			var target *int
+			switch c.Name {
+			case "min-sdk", "min-sdk-version":
+				target = &min
+			case "max-sdk", "max-sdk-version":
+				target = &max
+			default:
+				continue
+			}
+
+			val, prs := c.attr("value")
+			if !prs {
+				continue
+			}
+
+			*target, err = strconv.Atoi(val)
+			if err != nil {
+				return -1, -1, fmt.Errorf("Invalid %s value '%s': %s", c.Name, val, err.Error())
+			}
+		}
+	}
+	return
+}
+
+// Returns the country codes from the <dist:user-countries> delivery condition. include is false
+// when the module is delivered everywhere except these countries (dist:exclude="true").
+//
+// Returns nil countries and include == true if there is no country condition.
+func (a *APK) UserCountryTargeting() (countries []string, include bool, err error) {
+	include = true
+	for _, cond := range a.distConditions() {
+		for _, uc := range cond.children("user-countries") {
+			if val, prs := uc.attr("exclude"); prs {
+				exclude, err := strconv.ParseBool(val)
+				if err != nil {
+					return nil, true, fmt.Errorf("Invalid user-countries exclude value '%s': %s", val, err.Error())
+				}
+				include = !exclude
+			} else if val, prs := uc.attr("include"); prs {
+				if include, err = strconv.ParseBool(val); err != nil {
+					return nil, true, fmt.Errorf("Invalid user-countries include value '%s': %s", val, err.Error())
+				}
+			}
+
+			for _, c := range uc.children("country") {
+				if code := c.attrOrEmpty("code"); code != "" {
+					countries = append(countries, code)
+				}
+			}
+		}
+	}
+	return
+}
+
+// Returns the names of device features from the <dist:device-feature> delivery conditions,
+// the module is only delivered to devices which have all of them.
+func (a *APK) DeviceFeatureTargeting() ([]string, error) {
+	var res []string
+	for _, cond := range a.distConditions() {
+		for _, f := range cond.children("device-feature") {
+			if name := f.attrOrEmpty("name"); name != "" {
+				res = append(res, name)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Returns the user types (e.g. USER_TYPE_REGISTERED) from the <dist:user-type> delivery conditions.
+func (a *APK) UserTypesTargeting() ([]string, error) {
+	var res []string
+	for _, cond := range a.distConditions() {
+		for _, u := range cond.children("user-type") {
+			if typ := u.attrOrEmpty("type"); typ != "" {
+				res = append(res, typ)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,520 @@
+package apkparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	dexHeaderSize = 0x70
+)
+
+// Parsed string and type pools of one classes.dex file.
+type dexFile struct {
+	Name string
+
+	strings []string
+	// Type descriptors, e.g. Ljava/lang/Object;
+	types []string
+	// Classes defined in this file.
+	classes []dexClassDef
+	methods []dexMethodId
+
+	data []byte
+
+	code    []dexMethodCode
+	codeErr error
+}
+
+type dexClassDef struct {
+	Type          uint32
+	Superclass    uint32
+	InterfacesOff uint32
+	ClassDataOff  uint32
+}
+
+type dexMethodId struct {
+	Class uint16
+	Proto uint16
+	Name  uint32
+}
+
+// References from the code of one method.
+type dexMethodCode struct {
+	Class  uint32
+	Method uint32
+	// Length of the code in 16-bit units
+	Size uint32
+	// Indexes of strings loaded by const-string
+	Strings []uint32
+	// Indexes of invoked methods
+	Invokes []uint32
+	// Literals loaded by const/4, const/16, const and const/high16
+	Ints []int32
+}
+
+func parseDex(name string, data []byte) (*dexFile, error) {
+	if len(data) < dexHeaderSize || !bytes.HasPrefix(data, []byte("dex\n")) {
+		return nil, fmt.Errorf("%s: Invalid dex header.", name)
+	}
+
+	if binary.LittleEndian.Uint32(data[0x28:]) != 0x12345678 {
+		return nil, fmt.Errorf("%s: Unsupported endian tag.", name)
+	}
+
+	d := &dexFile{
+		Name: name,
+		data: data,
+	}
+
+	stringIds, err := dexSection(data, 0x38, 4)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Invalid string ids: %s", name, err.Error())
+	}
+
+	d.strings = make([]string, len(stringIds)/4)
+	for i := range d.strings {
+		off := binary.LittleEndian.Uint32(stringIds[i*4:])
+		if d.strings[i], err = readDexString(data, off); err != nil {
+			return nil, fmt.Errorf("%s: Invalid string %d: %s", name, i, err.Error())
+		}
+	}
+
+	typeIds, err := dexSection(data, 0x40, 4)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Invalid type ids: %s", name, err.Error())
+	}
+
+	d.types = make([]string, len(typeIds)/4)
+	for i := range d.types {
+		idx := binary.LittleEndian.Uint32(typeIds[i*4:])
+		if idx >= uint32(len(d.strings)) {
+			return nil, fmt.Errorf("%s: Type %d descriptor out of bounds.", name, i)
+		}
+		d.types[i] = d.strings[idx]
+	}
+
+	classDefs, err := dexSection(data, 0x60, 0x20)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Invalid class defs: %s", name, err.Error())
+	}
+
+	d.classes = make([]dexClassDef, len(classDefs)/0x20)
+	for i := range d.classes {
+		def := classDefs[i*0x20:]
+		d.classes[i] = dexClassDef{
+			Type:          binary.LittleEndian.Uint32(def),
+			Superclass:    binary.LittleEndian.Uint32(def[0x08:]),
+			InterfacesOff: binary.LittleEndian.Uint32(def[0x0c:]),
+			ClassDataOff:  binary.LittleEndian.Uint32(def[0x18:]),
+		}
+		if d.classes[i].Type >= uint32(len(d.types)) {
+			return nil, fmt.Errorf("%s: Class %d type out of bounds.", name, i)
+		}
+	}
+
+	methodIds, err := dexSection(data, 0x58, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Invalid method ids: %s", name, err.Error())
+	}
+
+	d.methods = make([]dexMethodId, len(methodIds)/8)
+	for i := range d.methods {
+		d.methods[i] = dexMethodId{
+			Class: binary.LittleEndian.Uint16(methodIds[i*8:]),
+			Proto: binary.LittleEndian.Uint16(methodIds[i*8+2:]),
+			Name:  binary.LittleEndian.Uint32(methodIds[i*8+4:]),
+		}
+		if uint32(d.methods[i].Class) >= uint32(len(d.types)) || d.methods[i].Name >= uint32(len(d.strings)) {
+			return nil, fmt.Errorf("%s: Method %d out of bounds.", name, i)
+		}
+	}
+	return d, nil
+}
+
+// Returns the section described by the size/offset pair at hdrOffset in the header.
+func dexSection(data []byte, hdrOffset int, itemSize uint32) ([]byte, error) {
+	count := binary.LittleEndian.Uint32(data[hdrOffset:])
+	offset := binary.LittleEndian.Uint32(data[hdrOffset+4:])
+	if count == 0 {
+		return nil, nil
+	}
+
+	size := uint64(count) * uint64(itemSize)
+	if uint64(offset)+size > uint64(len(data)) {
+		return nil, fmt.Errorf("out of bounds (%d items at 0x%x)", count, offset)
+	}
+	return data[offset : uint64(offset)+size], nil
+}
+
+func readUleb128(data []byte, off uint32) (val uint32, next uint32, err error) {
+	for i := uint32(0); i < 5; i++ {
+		if off+i >= uint32(len(data)) {
+			return 0, 0, fmt.Errorf("uleb128 at 0x%x out of bounds", off)
+		}
+		b := data[off+i]
+		val |= uint32(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return val, off + i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("uleb128 at 0x%x is too long", off)
+}
+
+// Decodes the MUTF-8 string_data_item at offset.
+func readDexString(data []byte, off uint32) (string, error) {
+	utf16Len, off, err := readUleb128(data, off)
+	if err != nil {
+		return "", err
+	}
+
+	end := bytes.IndexByte(data[off:], 0)
+	if end == -1 {
+		return "", fmt.Errorf("string at 0x%x is not terminated", off)
+	}
+	raw := data[off : off+uint32(end)]
+
+	ascii := true
+	for _, b := range raw {
+		if b >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return string(raw), nil
+	}
+
+	units := make([]uint16, 0, utf16Len)
+	for i := 0; i < len(raw); {
+		b := raw[i]
+		switch {
+		case b < 0x80:
+			units = append(units, uint16(b))
+			i++
+		case b&0xe0 == 0xc0 && i+1 < len(raw):
+			units = append(units, uint16(b&0x1f)<<6|uint16(raw[i+1]&0x3f))
+			i += 2
+		case b&0xf0 == 0xe0 && i+2 < len(raw):
+			units = append(units, uint16(b&0x0f)<<12|uint16(raw[i+1]&0x3f)<<6|uint16(raw[i+2]&0x3f))
+			i += 3
+		default:
+			return "", fmt.Errorf("invalid MUTF-8 sequence at 0x%x", off+uint32(i))
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// Converts a class name like com.example.Foo to its type descriptor prefix Lcom/example/Foo.
+func classNameToDescriptor(name string) string {
+	return "L" + strings.Replace(name, ".", "/", -1)
+}
+
+// Converts a type descriptor like Lcom/example/Foo; to class name com.example.Foo.
+// Returns "" for primitive and array types.
+func descriptorToClassName(desc string) string {
+	if len(desc) < 3 || desc[0] != 'L' || desc[len(desc)-1] != ';' {
+		return ""
+	}
+	return strings.Replace(desc[1:len(desc)-1], "/", ".", -1)
+}
+
+// Returns true if this file references any type that is the class or is in the package
+// prefix (e.g. com.example or com.example.Foo).
+func (d *dexFile) hasTypePrefix(prefix string) bool {
+	desc := classNameToDescriptor(prefix)
+	for _, t := range d.types {
+		if len(t) > len(desc) && strings.HasPrefix(t, desc) {
+			switch t[len(desc)] {
+			case '/', ';', '$':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns a deep copy of the parsed file. The code references are parsed again when needed.
+func (d *dexFile) clone() *dexFile {
+	return &dexFile{
+		Name:    d.Name,
+		strings: append([]string(nil), d.strings...),
+		types:   append([]string(nil), d.types...),
+		classes: append([]dexClassDef(nil), d.classes...),
+		methods: append([]dexMethodId(nil), d.methods...),
+		data:    append([]byte(nil), d.data...),
+	}
+}
+
+// Returns the string and method references from code of all methods in this file.
+func (d *dexFile) methodsCode() ([]dexMethodCode, error) {
+	if d.code != nil || d.codeErr != nil {
+		return d.code, d.codeErr
+	}
+
+	d.code = []dexMethodCode{}
+	for i := range d.classes {
+		if err := d.parseClassData(&d.classes[i]); err != nil {
+			d.code, d.codeErr = nil, fmt.Errorf("%s: Invalid class %s data: %s", d.Name, d.types[d.classes[i].Type], err.Error())
+			break
+		}
+	}
+	return d.code, d.codeErr
+}
+
+func (d *dexFile) parseClassData(cls *dexClassDef) error {
+	if cls.ClassDataOff == 0 {
+		return nil
+	}
+
+	var sizes [4]uint32
+	var err error
+	off := cls.ClassDataOff
+	for i := range sizes {
+		if sizes[i], off, err = readUleb128(d.data, off); err != nil {
+			return err
+		}
+	}
+
+	// static and instance fields: field_idx_diff, access_flags
+	for i := uint32(0); i < 2*(sizes[0]+sizes[1]); i++ {
+		if _, off, err = readUleb128(d.data, off); err != nil {
+			return err
+		}
+	}
+
+	// direct and virtual methods: method_idx_diff, access_flags, code_off
+	for _, count := range sizes[2:] {
+		var methodIdx uint32
+		for i := uint32(0); i < count; i++ {
+			var diff, codeOff uint32
+			if diff, off, err = readUleb128(d.data, off); err != nil {
+				return err
+			}
+			if _, off, err = readUleb128(d.data, off); err != nil {
+				return err
+			}
+			if codeOff, off, err = readUleb128(d.data, off); err != nil {
+				return err
+			}
+
+			methodIdx += diff
+			if codeOff == 0 {
+				continue
+			}
+
+			code := dexMethodCode{
+				Class:  cls.Type,
+				Method: methodIdx,
+			}
+			if err := d.parseCode(codeOff, &code); err != nil {
+				return fmt.Errorf("method %d: %s", methodIdx, err.Error())
+			}
+			d.code = append(d.code, code)
+		}
+	}
+	return nil
+}
+
+// Walks the instructions of code_item at off, collecting const-string and invoke references.
+func (d *dexFile) parseCode(off uint32, code *dexMethodCode) error {
+	if uint64(off)+16 > uint64(len(d.data)) {
+		return fmt.Errorf("code item out of bounds")
+	}
+
+	insnsSize := binary.LittleEndian.Uint32(d.data[off+12:])
+	if uint64(off)+16+2*uint64(insnsSize) > uint64(len(d.data)) {
+		return fmt.Errorf("instructions out of bounds")
+	}
+	insns := d.data[off+16 : off+16+2*insnsSize]
+	code.Size = insnsSize
+
+	unit := func(i uint32) uint32 {
+		return uint32(binary.LittleEndian.Uint16(insns[2*i:]))
+	}
+
+	for i := uint32(0); i < insnsSize; {
+		op := unit(i) & 0xff
+
+		// In 64 bits, the payload sizes of crafted dex files would wrap around.
+		var width uint64
+		switch ident := unit(i); {
+		case ident == 0x0100 && i+1 < insnsSize: // packed-switch-payload
+			width = uint64(unit(i+1))*2 + 4
+		case ident == 0x0200 && i+1 < insnsSize: // sparse-switch-payload
+			width = uint64(unit(i+1))*4 + 2
+		case ident == 0x0300 && i+3 < insnsSize: // fill-array-data-payload
+			size := unit(i+2) | unit(i+3)<<16
+			width = (uint64(size)*uint64(unit(i+1))+1)/2 + 4
+		default:
+			width = uint64(dexInsnWidth(uint8(op)))
+		}
+
+		if width == 0 || uint64(i)+width > uint64(insnsSize) {
+			break
+		}
+
+		switch {
+		case op == 0x1a: // const-string
+			code.Strings = append(code.Strings, unit(i+1))
+		case op == 0x1b: // const-string/jumbo
+			code.Strings = append(code.Strings, unit(i+1)|unit(i+2)<<16)
+		case op == 0x12: // const/4
+			code.Ints = append(code.Ints, int32(int16(unit(i)))>>12)
+		case op == 0x13: // const/16
+			code.Ints = append(code.Ints, int32(int16(unit(i+1))))
+		case op == 0x14: // const
+			code.Ints = append(code.Ints, int32(unit(i+1)|unit(i+2)<<16))
+		case op == 0x15: // const/high16
+			code.Ints = append(code.Ints, int32(unit(i+1)<<16))
+		case op >= 0x6e && op <= 0x72, op >= 0x74 && op <= 0x78, op == 0xfa, op == 0xfb: // invoke-*
+			code.Invokes = append(code.Invokes, unit(i+1))
+		}
+		i += uint32(width)
+	}
+
+	for _, idx := range code.Strings {
+		if idx >= uint32(len(d.strings)) {
+			return fmt.Errorf("const-string index %d out of bounds", idx)
+		}
+	}
+	for _, idx := range code.Invokes {
+		if idx >= uint32(len(d.methods)) {
+			return fmt.Errorf("invoked method index %d out of bounds", idx)
+		}
+	}
+	return nil
+}
+
+// Returns the instruction width in 16-bit code units.
+func dexInsnWidth(op uint8) uint32 {
+	switch {
+	case op == 0x02, op == 0x05, op == 0x08, op == 0x13, op == 0x15, op == 0x16, op == 0x19,
+		op == 0x1a, op == 0x1c, op == 0x1f, op == 0x20, op == 0x22, op == 0x23, op == 0x29:
+		return 2
+	case op == 0x03, op == 0x06, op == 0x09, op == 0x14, op == 0x17, op == 0x1b,
+		op >= 0x24 && op <= 0x26, op >= 0x2a && op <= 0x2c:
+		return 3
+	case op == 0x18:
+		return 5
+	case op >= 0x2d && op <= 0x3d, op >= 0x44 && op <= 0x6d:
+		return 2
+	case op >= 0x6e && op <= 0x72, op >= 0x74 && op <= 0x78:
+		return 3
+	case op >= 0x90 && op <= 0xaf, op >= 0xd0 && op <= 0xe2:
+		return 2
+	case op == 0xfa, op == 0xfb:
+		return 4
+	case op == 0xfc, op == 0xfd:
+		return 3
+	case op == 0xfe, op == 0xff:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Returns the type descriptors of interfaces the class implements.
+func (d *dexFile) classInterfaces(cls *dexClassDef) []string {
+	if cls.InterfacesOff == 0 || uint64(cls.InterfacesOff)+4 > uint64(len(d.data)) {
+		return nil
+	}
+
+	count := binary.LittleEndian.Uint32(d.data[cls.InterfacesOff:])
+	if uint64(cls.InterfacesOff)+4+2*uint64(count) > uint64(len(d.data)) {
+		return nil
+	}
+
+	res := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		idx := binary.LittleEndian.Uint16(d.data[cls.InterfacesOff+4+2*i:])
+		if uint32(idx) < uint32(len(d.types)) {
+			res = append(res, d.types[idx])
+		}
+	}
+	return res
+}
+
+// Returns true if the method calls class.name, class is the type descriptor like Ljava/lang/Class;
+func (d *dexFile) invokes(code *dexMethodCode, class, name string) bool {
+	for _, idx := range code.Invokes {
+		m := &d.methods[idx]
+		if d.strings[m.Name] == name && d.types[m.Class] == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the method loads this integer constant.
+func (code *dexMethodCode) loadsInt(val int32) bool {
+	for _, v := range code.Ints {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the method calls a method with this name of any class. Useful for methods
+// called through app's subclasses, like getSharedPreferences on an Activity.
+func (d *dexFile) invokesName(code *dexMethodCode, name string) bool {
+	for _, idx := range code.Invokes {
+		if d.strings[d.methods[idx].Name] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Compiler which produced a dex file, from the marker string D8 and R8 put into the string pool.
+type DEXCompilerInfo struct {
+	File string
+	// "d8", "r8", "l8" or "" if the file has no marker, which usually means it was made by dx.
+	Compiler        string
+	Version         string
+	CompilationMode string
+	MinApi          int
+}
+
+// Returns the compiler info for each of the dex files.
+func (a *APK) DexCompilerInfo() ([]DEXCompilerInfo, error) {
+	files, err := a.dexFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]DEXCompilerInfo, 0, len(files))
+	for _, d := range files {
+		info := DEXCompilerInfo{File: d.Name}
+
+		// Merged files may have multiple markers, R8 is the most interesting one.
+		var marker string
+		for _, s := range d.strings {
+			if strings.HasPrefix(s, "~~") && len(s) > 4 && s[4] == '{' && (marker == "" || s[:4] == "~~R8") {
+				marker = s
+			}
+		}
+
+		if marker != "" {
+			info.Compiler = strings.ToLower(marker[2:4])
+
+			var values struct {
+				CompilationMode string `json:"compilation-mode"`
+				MinApi          int    `json:"min-api"`
+				Version         string `json:"version"`
+			}
+			if err := json.Unmarshal([]byte(marker[4:]), &values); err == nil {
+				info.Version = values.Version
+				info.CompilationMode = values.CompilationMode
+				info.MinApi = values.MinApi
+			}
+		}
+		res = append(res, info)
+	}
+	return res, nil
+}
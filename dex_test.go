@@ -0,0 +1,322 @@
+package apkparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type testDexMethod struct {
+	class, name string
+}
+
+type testDexCode struct {
+	method int
+	insns  []uint16
+}
+
+type testDexClass struct {
+	typ, super string
+	interfaces []string
+	methods    []testDexCode
+}
+
+func writeUleb128(b *bytes.Buffer, v uint32) {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			b.WriteByte(c)
+			return
+		}
+		b.WriteByte(c | 0x80)
+	}
+}
+
+// Builds a minimal classes.dex. Types, method classes and names are looked up in strs by value.
+// Only the header fields and sections parseDex reads are filled in.
+func buildDex(strs, types []string, methods []testDexMethod, classes []testDexClass) []byte {
+	strIdx := make(map[string]uint32)
+	for i, s := range strs {
+		strIdx[s] = uint32(i)
+	}
+	typeIdx := make(map[string]uint32)
+	for i, t := range types {
+		typeIdx[t] = uint32(i)
+	}
+
+	le := binary.LittleEndian
+	strIdsOff := uint32(dexHeaderSize)
+	typeIdsOff := strIdsOff + 4*uint32(len(strs))
+	methodIdsOff := typeIdsOff + 4*uint32(len(types))
+	classDefsOff := methodIdsOff + 8*uint32(len(methods))
+	dataOff := classDefsOff + 0x20*uint32(len(classes))
+
+	var data bytes.Buffer
+	pos := func() uint32 { return dataOff + uint32(data.Len()) }
+	align := func() {
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+
+	strOffs := make([]uint32, len(strs))
+	for i, s := range strs {
+		strOffs[i] = pos()
+		writeUleb128(&data, uint32(len(s)))
+		data.WriteString(s)
+		data.WriteByte(0)
+	}
+
+	ifaceOffs := make([]uint32, len(classes))
+	codeOffs := make([][]uint32, len(classes))
+	for i, c := range classes {
+		if len(c.interfaces) != 0 {
+			align()
+			ifaceOffs[i] = pos()
+			binary.Write(&data, le, uint32(len(c.interfaces)))
+			for _, iface := range c.interfaces {
+				binary.Write(&data, le, uint16(typeIdx[iface]))
+			}
+		}
+
+		for _, m := range c.methods {
+			align()
+			codeOffs[i] = append(codeOffs[i], pos())
+			// registers, ins, outs, tries, debug info, insns size
+			binary.Write(&data, le, [4]uint16{1, 0, 0, 0})
+			binary.Write(&data, le, uint32(0))
+			binary.Write(&data, le, uint32(len(m.insns)))
+			binary.Write(&data, le, m.insns)
+		}
+	}
+
+	classDataOffs := make([]uint32, len(classes))
+	for i, c := range classes {
+		classDataOffs[i] = pos()
+		writeUleb128(&data, 0)
+		writeUleb128(&data, 0)
+		writeUleb128(&data, uint32(len(c.methods)))
+		writeUleb128(&data, 0)
+		prev := 0
+		for j, m := range c.methods {
+			writeUleb128(&data, uint32(m.method-prev))
+			writeUleb128(&data, 1)
+			writeUleb128(&data, codeOffs[i][j])
+			prev = m.method
+		}
+	}
+
+	hdr := make([]byte, dexHeaderSize)
+	copy(hdr, "dex\n035\x00")
+	le.PutUint32(hdr[0x28:], 0x12345678)
+	le.PutUint32(hdr[0x38:], uint32(len(strs)))
+	le.PutUint32(hdr[0x3c:], strIdsOff)
+	le.PutUint32(hdr[0x40:], uint32(len(types)))
+	le.PutUint32(hdr[0x44:], typeIdsOff)
+	le.PutUint32(hdr[0x58:], uint32(len(methods)))
+	le.PutUint32(hdr[0x5c:], methodIdsOff)
+	le.PutUint32(hdr[0x60:], uint32(len(classes)))
+	le.PutUint32(hdr[0x64:], classDefsOff)
+
+	var out bytes.Buffer
+	out.Write(hdr)
+	for _, off := range strOffs {
+		binary.Write(&out, le, off)
+	}
+	for _, t := range types {
+		binary.Write(&out, le, strIdx[t])
+	}
+	for _, m := range methods {
+		binary.Write(&out, le, uint16(typeIdx[m.class]))
+		binary.Write(&out, le, uint16(0))
+		binary.Write(&out, le, strIdx[m.name])
+	}
+	for i, c := range classes {
+		super := uint32(0xffffffff)
+		if c.super != "" {
+			super = typeIdx[c.super]
+		}
+		binary.Write(&out, le, [8]uint32{typeIdx[c.typ], 1, super, ifaceOffs[i], 0xffffffff, 0, classDataOffs[i], 0})
+	}
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+func TestDexMethodsCode(t *testing.T) {
+	strs := []string{"Lcom/example/Main;", "Ljava/lang/Object;", "run", "invoke", "a", "b"}
+	types := []string{"Lcom/example/Main;", "Ljava/lang/Object;"}
+	methods := []testDexMethod{{"Lcom/example/Main;", "run"}, {"Ljava/lang/Object;", "invoke"}}
+
+	tests := []struct {
+		name    string
+		insns   []uint16
+		strings []uint32
+		ints    []int32
+		invokes []uint32
+		err     bool
+	}{
+		{
+			name:    "const-string",
+			insns:   []uint16{0x001a, 4, 0x011a, 5, 0x000e},
+			strings: []uint32{4, 5},
+		},
+		{
+			name:    "const-string/jumbo",
+			insns:   []uint16{0x001b, 5, 0, 0x000e},
+			strings: []uint32{5},
+		},
+		{
+			name:  "const/4 sign extension",
+			insns: []uint16{0x7012, 0x8012, 0xf012, 0x000e},
+			ints:  []int32{7, -8, -1},
+		},
+		{
+			name:  "const/16, const and const/high16",
+			insns: []uint16{0x0013, 0xfffe, 0x0014, 0x2000, 0x0001, 0x0015, 0x7f01, 0x000e},
+			ints:  []int32{-2, 0x12000, 0x7f010000},
+		},
+		{
+			name:    "invoke-virtual and invoke-static/range",
+			insns:   []uint16{0x106e, 1, 0, 0x0177, 0, 0, 0x000e},
+			invokes: []uint32{1, 0},
+		},
+		{
+			// invoke-polymorphic is 4 units wide, the string after it must not be lost
+			name:    "invoke-polymorphic",
+			insns:   []uint16{0x10fa, 1, 0, 0, 0x00fb, 0, 0, 0, 0x001a, 4, 0x000e},
+			strings: []uint32{4},
+			invokes: []uint32{1, 0},
+		},
+		{
+			// invoke-custom references a call site, not a method
+			name:    "invoke-custom",
+			insns:   []uint16{0x10fc, 7, 0, 0x00fd, 7, 0, 0x001a, 5, 0x000e},
+			strings: []uint32{5},
+		},
+		{
+			// 4 + 2*size units; the 0x001a inside must not be read as const-string
+			name:    "packed-switch payload",
+			insns:   []uint16{0x000e, 0x0000, 0x0100, 2, 0, 0, 0x001a, 0, 0x001a, 0, 0x001a, 4},
+			strings: []uint32{4},
+		},
+		{
+			// 2 + 4*size units
+			name:    "sparse-switch payload",
+			insns:   []uint16{0x000e, 0x0000, 0x0200, 1, 0x001a, 0, 0x001a, 0, 0x001a, 5},
+			strings: []uint32{5},
+		},
+		{
+			// 4 + (size*width+1)/2 units, 3 one-byte elements take 2 units
+			name:    "fill-array-data payload",
+			insns:   []uint16{0x000e, 0x0000, 0x0300, 1, 3, 0, 0x1a1a, 0x001a, 0x001a, 4},
+			strings: []uint32{4},
+		},
+		{
+			// element_width 4, size 0xfffffffe: the width wraps to 0 in 32 bits
+			name:  "fill-array-data payload width overflow",
+			insns: []uint16{0x000e, 0x0000, 0x0300, 4, 0xfffe, 0xffff, 0x001a, 4},
+		},
+		{
+			// i+width wraps to 0 in 32 bits
+			name:  "fill-array-data payload end overflow",
+			insns: []uint16{0x000e, 0x0000, 0x0000, 0x0000, 0x0300, 2, 0xfff8, 0xffff, 0x001a, 4},
+		},
+		{
+			name:    "truncated instruction",
+			insns:   []uint16{0x001a, 4, 0x001b, 5},
+			strings: []uint32{4},
+		},
+		{
+			name:  "string index out of range",
+			insns: []uint16{0x001a, 6, 0x000e},
+			err:   true,
+		},
+		{
+			name:  "jumbo string index out of range",
+			insns: []uint16{0x001b, 0, 1, 0x000e},
+			err:   true,
+		},
+		{
+			name:  "method index out of range",
+			insns: []uint16{0x0071, 2, 0, 0x000e},
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		data := buildDex(strs, types, methods, []testDexClass{{
+			typ:     "Lcom/example/Main;",
+			super:   "Ljava/lang/Object;",
+			methods: []testDexCode{{0, tt.insns}},
+		}})
+
+		d, err := parseDex("classes.dex", data)
+		if err != nil {
+			t.Fatalf("%s: failed to parse dex: %s", tt.name, err.Error())
+		}
+
+		code, err := d.methodsCode()
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", tt.name, code)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err.Error())
+			continue
+		} else if len(code) != 1 {
+			t.Errorf("%s: expected 1 method, got %d", tt.name, len(code))
+			continue
+		}
+
+		c := code[0]
+		if c.Size != uint32(len(tt.insns)) {
+			t.Errorf("%s: size %d, expected %d", tt.name, c.Size, len(tt.insns))
+		}
+		if !reflect.DeepEqual(c.Strings, tt.strings) {
+			t.Errorf("%s: strings %v, expected %v", tt.name, c.Strings, tt.strings)
+		}
+		if !reflect.DeepEqual(c.Ints, tt.ints) {
+			t.Errorf("%s: ints %v, expected %v", tt.name, c.Ints, tt.ints)
+		}
+		if !reflect.DeepEqual(c.Invokes, tt.invokes) {
+			t.Errorf("%s: invokes %v, expected %v", tt.name, c.Invokes, tt.invokes)
+		}
+	}
+}
+
+func TestDexInsnWidth(t *testing.T) {
+	tests := []struct {
+		op    uint8
+		width uint32
+	}{
+		{0x00, 1}, // nop
+		{0x0e, 1}, // return-void
+		{0x12, 1}, // const/4
+		{0x13, 2}, // const/16
+		{0x14, 3}, // const
+		{0x18, 5}, // const-wide
+		{0x1a, 2}, // const-string
+		{0x1b, 3}, // const-string/jumbo
+		{0x26, 3}, // fill-array-data
+		{0x2b, 3}, // packed-switch
+		{0x2c, 3}, // sparse-switch
+		{0x6e, 3}, // invoke-virtual
+		{0x73, 1}, // unused
+		{0x78, 3}, // invoke-interface/range
+		{0xfa, 4}, // invoke-polymorphic
+		{0xfb, 4}, // invoke-polymorphic/range
+		{0xfc, 3}, // invoke-custom
+		{0xfd, 3}, // invoke-custom/range
+		{0xfe, 2}, // const-method-handle
+		{0xff, 2}, // const-method-type
+	}
+
+	for _, tt := range tests {
+		if w := dexInsnWidth(tt.op); w != tt.width {
+			t.Errorf("op 0x%02x: width %d, expected %d", tt.op, w, tt.width)
+		}
+	}
+}
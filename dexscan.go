@@ -0,0 +1,181 @@
+package apkparser
+
+import (
+	"regexp"
+)
+
+var (
+	classNameRegexp = regexp.MustCompile(`^([a-zA-Z_$][a-zA-Z0-9_$]*\.)+[A-Z][a-zA-Z0-9_$]*$`)
+
+	cryptoAlgorithmRegexps = []*regexp.Regexp{
+		// Cipher.getInstance
+		regexp.MustCompile(`^(AES|AES_128|AES_256|DES|DESede|TripleDES|RSA|RC2|RC4|ARCFOUR|Blowfish|ChaCha20|ChaCha20-Poly1305)(/[A-Za-z0-9]+/[A-Za-z0-9]+)?$`),
+		regexp.MustCompile(`^PBEWith[A-Za-z0-9]+And[A-Za-z0-9]+$`),
+		// MessageDigest.getInstance
+		regexp.MustCompile(`^(MD2|MD4|MD5|SHA|SHA-1|SHA1|SHA-224|SHA-256|SHA-384|SHA-512|SHA-512/224|SHA-512/256|SHA3-224|SHA3-256|SHA3-384|SHA3-512)$`),
+		// Mac.getInstance, SecretKeyFactory.getInstance
+		regexp.MustCompile(`^(PBKDF2With)?Hmac(MD5|SHA1|SHA224|SHA256|SHA384|SHA512)$`),
+		// Signature.getInstance
+		regexp.MustCompile(`^(NONE|MD5|SHA1|SHA224|SHA256|SHA384|SHA512)with(RSA|DSA|ECDSA)(/PSS)?$`),
+	}
+)
+
+// Calls fn for each string in string pools of all dex files.
+func (a *APK) walkDexStrings(fn func(d *dexFile, str string)) error {
+	files, err := a.dexFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range files {
+		for _, str := range d.strings {
+			fn(d, str)
+		}
+	}
+	return nil
+}
+
+// Calls fn for code of each method in all dex files.
+func (a *APK) walkMethodsCode(fn func(d *dexFile, code *dexMethodCode)) error {
+	files, err := a.dexFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range files {
+		codes, err := d.methodsCode()
+		if err != nil {
+			return err
+		}
+
+		for i := range codes {
+			fn(d, &codes[i])
+		}
+	}
+	return nil
+}
+
+// Returns the class names which are likely accessed through reflection: string constants
+// that look like class names, used in methods which call Class.forName, ClassLoader.loadClass
+// or Method.invoke.
+func (a *APK) JavaReflectionUsage() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if !d.invokes(code, "Ljava/lang/Class;", "forName") &&
+			!d.invokes(code, "Ljava/lang/ClassLoader;", "loadClass") &&
+			!d.invokes(code, "Ljava/lang/reflect/Method;", "invoke") {
+			return
+		}
+
+		for _, idx := range code.Strings {
+			str := d.strings[idx]
+			if !seen[str] && classNameRegexp.MatchString(str) {
+				seen[str] = true
+				res = append(res, str)
+			}
+		}
+	})
+	return res, err
+}
+
+// Returns the unique JCA algorithm names (e.g. "AES/CBC/PKCS5Padding", "SHA-256", "HmacSHA1")
+// found in the dex string pools. Useful to spot weak algorithms like DES, RC4 or MD5.
+func (a *APK) CryptoAlgorithms() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+
+	err := a.walkDexStrings(func(d *dexFile, str string) {
+		if seen[str] {
+			return
+		}
+
+		for _, re := range cryptoAlgorithmRegexps {
+			if re.MatchString(str) {
+				seen[str] = true
+				res = append(res, str)
+				return
+			}
+		}
+	})
+	return res, err
+}
+
+var keyStoreTypes = map[string]bool{
+	"AndroidKeyStore": true,
+	"AndroidCAStore":  true,
+	"BKS":             true,
+	"BKS-V1":          true,
+	"BouncyCastle":    true,
+	"UBER":            true,
+	"PKCS12":          true,
+	"JKS":             true,
+}
+
+// Returns the unique KeyStore types (e.g. "AndroidKeyStore", "PKCS12", "BKS") used in methods
+// which call KeyStore.getInstance. JKS and BKS usually mean a legacy crypto setup.
+func (a *APK) KeyStoreType() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if !d.invokes(code, "Ljava/security/KeyStore;", "getInstance") {
+			return
+		}
+
+		for _, idx := range code.Strings {
+			str := d.strings[idx]
+			if !seen[str] && keyStoreTypes[str] {
+				seen[str] = true
+				res = append(res, str)
+			}
+		}
+	})
+	return res, err
+}
+
+var prefsFileNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Returns candidate SharedPreferences file names: string constants that could be file names,
+// used in methods which call getSharedPreferences or EncryptedSharedPreferences.create.
+// The same methods often use the preference keys too, so not every result is a file name.
+func (a *APK) SharedPreferences() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if !d.invokesName(code, "getSharedPreferences") &&
+			!d.invokes(code, "Landroidx/security/crypto/EncryptedSharedPreferences;", "create") {
+			return
+		}
+
+		for _, idx := range code.Strings {
+			str := d.strings[idx]
+			if !seen[str] && prefsFileNameRegexp.MatchString(str) {
+				seen[str] = true
+				res = append(res, str)
+			}
+		}
+	})
+	return res, err
+}
+
+var contentUriRegexp = regexp.MustCompile(`content://[A-Za-z0-9._-]+(/[^"\s]*)?`)
+
+// Returns the unique content:// URIs found in the dex string pools, the content providers
+// (own or other apps') the app likely talks to.
+func (a *APK) ContentResolverURIs() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+
+	err := a.walkDexStrings(func(d *dexFile, str string) {
+		for _, uri := range contentUriRegexp.FindAllString(str, -1) {
+			if !seen[uri] {
+				seen[uri] = true
+				res = append(res, uri)
+			}
+		}
+	})
+	return res, err
+}
@@ -0,0 +1,74 @@
+package apkparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Returns an APK with just the parsed dex file, enough for the dex scanners.
+func dexAPK(t *testing.T, strs, types []string, methods []testDexMethod, classes []testDexClass) *APK {
+	d, err := parseDex("classes.dex", buildDex(strs, types, methods, classes))
+	if err != nil {
+		t.Fatalf("Failed to parse dex: %s", err.Error())
+	}
+	return &APK{dex: []*dexFile{d}}
+}
+
+func TestDexScanners(t *testing.T) {
+	strs := []string{
+		"Lcom/example/Main;", "Ljava/lang/Object;", "Ljava/lang/Class;", "Ljava/security/KeyStore;",
+		"forName", "getInstance", "run", "other",
+		"com.example.Hidden", "not a class", "BKS", "AndroidKeyStore", "content://com.example.provider/items",
+	}
+	types := []string{"Lcom/example/Main;", "Ljava/lang/Object;", "Ljava/lang/Class;", "Ljava/security/KeyStore;"}
+	methods := []testDexMethod{
+		{"Ljava/lang/Class;", "forName"},
+		{"Ljava/security/KeyStore;", "getInstance"},
+		{"Lcom/example/Main;", "run"},
+		{"Lcom/example/Main;", "other"},
+	}
+
+	a := dexAPK(t, strs, types, methods, []testDexClass{{
+		typ:   "Lcom/example/Main;",
+		super: "Ljava/lang/Object;",
+		methods: []testDexCode{
+			// const-string v0, "com.example.Hidden"; const-string v0, "not a class"; invoke-static {v0}, Class.forName
+			{2, []uint16{0x001a, 8, 0x001a, 9, 0x1071, 0, 0, 0x000e}},
+			// const-string v0, "BKS"; const-string v0, "com.example.Hidden"; invoke-static {v0}, KeyStore.getInstance
+			{3, []uint16{0x001a, 10, 0x001a, 8, 0x1071, 1, 0, 0x000e}},
+		},
+	}})
+
+	refl, err := a.JavaReflectionUsage()
+	if err != nil || !reflect.DeepEqual(refl, []string{"com.example.Hidden"}) {
+		t.Errorf("JavaReflectionUsage: %v %v", refl, err)
+	}
+
+	// AndroidKeyStore is in the string pool, but no method passes it to getInstance
+	keyStores, err := a.KeyStoreType()
+	if err != nil || !reflect.DeepEqual(keyStores, []string{"BKS"}) {
+		t.Errorf("KeyStoreType: %v %v", keyStores, err)
+	}
+
+	uris, err := a.ContentResolverURIs()
+	if err != nil || !reflect.DeepEqual(uris, []string{"content://com.example.provider/items"}) {
+		t.Errorf("ContentResolverURIs: %v %v", uris, err)
+	}
+}
+
+func TestDexScannersInvalidCode(t *testing.T) {
+	strs := []string{"Lcom/example/Main;", "Ljava/lang/Object;", "run"}
+	types := []string{"Lcom/example/Main;", "Ljava/lang/Object;"}
+	methods := []testDexMethod{{"Lcom/example/Main;", "run"}}
+
+	// const-string with index past the string pool
+	a := dexAPK(t, strs, types, methods, []testDexClass{{
+		typ:     "Lcom/example/Main;",
+		super:   "Ljava/lang/Object;",
+		methods: []testDexCode{{0, []uint16{0x001a, 100, 0x000e}}},
+	}})
+
+	if res, err := a.JavaReflectionUsage(); err == nil {
+		t.Errorf("Expected an error, got %v", res)
+	}
+}
@@ -0,0 +1,72 @@
+package apkparser
+
+import (
+	"encoding/json"
+)
+
+// The parts of google-services.json this library uses.
+type googleServicesJson struct {
+	ProjectInfo struct {
+		ProjectId     string `json:"project_id"`
+		ProjectNumber string `json:"project_number"`
+	} `json:"project_info"`
+	Client []struct {
+		ApiKey []struct {
+			CurrentKey string `json:"current_key"`
+		} `json:"api_key"`
+	} `json:"client"`
+}
+
+// Returns parsed google-services.json, if the app bundles it in assets. The Google Services
+// gradle plugin normally converts it to string resources instead.
+func (a *APK) googleServicesJson() *googleServicesJson {
+	for _, name := range []string{"assets/google-services.json", "google-services.json"} {
+		data, err := a.readFile(name)
+		if err != nil {
+			continue
+		}
+
+		var res googleServicesJson
+		if err := json.Unmarshal(data, &res); err == nil {
+			return &res
+		}
+	}
+	return nil
+}
+
+// Returns the Firebase project ID, from google-services.json in assets, the project_id string
+// resource generated by the Google Services plugin or com.google.firebase.PROJECT_ID meta-data.
+//
+// Returns ErrNotFound if none of them is present.
+func (a *APK) FirebaseProjectID() (string, error) {
+	if gs := a.googleServicesJson(); gs != nil && gs.ProjectInfo.ProjectId != "" {
+		return gs.ProjectInfo.ProjectId, nil
+	}
+
+	if id, err := a.stringResource("project_id"); err == nil && id != "" {
+		return id, nil
+	} else if err != nil && err != ErrNotFound {
+		return "", err
+	}
+
+	if id, prs := a.metaData("com.google.firebase.PROJECT_ID"); prs && id != "" {
+		return id, nil
+	}
+	return "", ErrNotFound
+}
+
+// Returns the Firebase Cloud Messaging sender ID (the project number), from google-services.json
+// in assets or the gcm_defaultSenderId string resource generated by the Google Services plugin.
+//
+// Returns ErrNotFound if neither is present.
+func (a *APK) FCMSenderID() (string, error) {
+	if gs := a.googleServicesJson(); gs != nil && gs.ProjectInfo.ProjectNumber != "" {
+		return gs.ProjectInfo.ProjectNumber, nil
+	}
+
+	id, err := a.stringResource("gcm_defaultSenderId")
+	if err == nil && id == "" {
+		return "", ErrNotFound
+	}
+	return id, err
+}
@@ -0,0 +1,337 @@
+package apkparser
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// JSON form of the APK, see APK.MarshalJSON.
+type apkJson struct {
+	Manifest       *xmlElementJson    `json:"manifest"`
+	Resources      *resourceTableJson `json:"resources,omitempty"`
+	ResourcesError string             `json:"resourcesError,omitempty"`
+}
+
+type xmlElementJson struct {
+	Name     string            `json:"name"`
+	Attrs    []xmlAttrJson     `json:"attrs,omitempty"`
+	Children []*xmlElementJson `json:"children,omitempty"`
+	Text     string            `json:"text,omitempty"`
+}
+
+type xmlAttrJson struct {
+	Space string `json:"space,omitempty"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Raw type and data, nil type if the element doesn't have them
+	Type *AttrType `json:"type,omitempty"`
+	Data uint32    `json:"data,omitempty"`
+}
+
+type resourceTableJson struct {
+	MainStrings   stringTableJson    `json:"mainStrings"`
+	NextPackageId uint32             `json:"nextPackageId"`
+	Packages      []packageGroupJson `json:"packages,omitempty"`
+}
+
+type stringTableJson struct {
+	IsUtf8        bool   `json:"isUtf8,omitempty"`
+	StringOffsets []byte `json:"stringOffsets,omitempty"`
+	Data          []byte `json:"data,omitempty"`
+	// Empty tables have no cache, see StringTable.isEmpty
+	Empty bool `json:"empty,omitempty"`
+}
+
+type packageGroupJson struct {
+	Name          string                 `json:"name"`
+	Id            uint32                 `json:"id"`
+	LargestTypeId uint8                  `json:"largestTypeId"`
+	Packages      []resourcePackageJson  `json:"packages,omitempty"`
+	Types         []resourceTypeSpecJson `json:"types,omitempty"`
+}
+
+type resourcePackageJson struct {
+	Id           uint32          `json:"id"`
+	Name         string          `json:"name"`
+	TypeIdOffset uint32          `json:"typeIdOffset,omitempty"`
+	TypeStrings  stringTableJson `json:"typeStrings"`
+	KeyStrings   stringTableJson `json:"keyStrings"`
+}
+
+type resourceTypeSpecJson struct {
+	Id      uint8              `json:"id"`
+	Entries []uint32           `json:"entries,omitempty"`
+	Package int                `json:"package"` // index into packageGroupJson.Packages
+	Configs []resourceTypeJson `json:"configs,omitempty"`
+}
+
+type resourceTypeJson struct {
+	ChunkData    []byte `json:"chunkData"`
+	EntryCount   uint32 `json:"entryCount"`
+	EntriesStart uint32 `json:"entriesStart"`
+	IndexesStart uint32 `json:"indexesStart"`
+	Density      uint16 `json:"density,omitempty"`
+	Language     string `json:"language,omitempty"`
+	Country      string `json:"country,omitempty"`
+}
+
+// Serializes the parsed manifest and resources, so they can be cached without parsing the APK again.
+//
+// Not serialized are the zip file, dex files and context. Methods of unmarshaled APK which
+// need to read other files from the zip work as if the APK didn't contain them.
+func (a *APK) MarshalJSON() ([]byte, error) {
+	if a.manifest == nil {
+		return nil, errNoManifest
+	}
+
+	res := apkJson{
+		Manifest: a.manifest.toJson(),
+	}
+
+	if a.resources != nil {
+		res.Resources = a.resources.toJson()
+	}
+	if a.resourcesErr != nil {
+		res.ResourcesError = a.resourcesErr.Error()
+	}
+	return json.Marshal(&res)
+}
+
+// Loads the APK serialized by MarshalJSON. The APK doesn't have to be closed.
+func (a *APK) UnmarshalJSON(data []byte) error {
+	var js apkJson
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+
+	if js.Manifest == nil || js.Manifest.Name != "manifest" {
+		return fmt.Errorf("Invalid APK JSON, missing manifest.")
+	}
+
+	*a = APK{
+		zip: &ZipReader{
+			File: make(map[string]*ZipReaderFile),
+		},
+		manifest: js.Manifest.toElement(nil),
+	}
+
+	if js.Resources != nil {
+		var err error
+		if a.resources, err = js.Resources.toTable(); err != nil {
+			return err
+		}
+	}
+
+	switch js.ResourcesError {
+	case "":
+	case os.ErrNotExist.Error():
+		a.resourcesErr = os.ErrNotExist
+	default:
+		a.resourcesErr = errors.New(js.ResourcesError)
+	}
+	return nil
+}
+
+func (e *xmlElement) toJson() *xmlElementJson {
+	res := &xmlElementJson{
+		Name: e.Name,
+		Text: e.Text,
+	}
+	for i, attr := range e.Attrs {
+		js := xmlAttrJson{Space: attr.Name.Space, Name: attr.Name.Local, Value: attr.Value}
+		if e.attrTypes != nil {
+			js.Type, js.Data = &e.attrTypes[i], e.attrData[i]
+		}
+		res.Attrs = append(res.Attrs, js)
+	}
+	for _, c := range e.Children {
+		res.Children = append(res.Children, c.toJson())
+	}
+	return res
+}
+
+func (e *xmlElementJson) toElement(parent *xmlElement) *xmlElement {
+	res := &xmlElement{
+		Name:   e.Name,
+		Text:   e.Text,
+		parent: parent,
+	}
+	raw := len(e.Attrs) != 0
+	for _, attr := range e.Attrs {
+		res.Attrs = append(res.Attrs, xml.Attr{Name: xml.Name{Space: attr.Space, Local: attr.Name}, Value: attr.Value})
+		raw = raw && attr.Type != nil
+	}
+	if raw {
+		for _, attr := range e.Attrs {
+			res.attrTypes = append(res.attrTypes, *attr.Type)
+			res.attrData = append(res.attrData, attr.Data)
+		}
+	}
+	for _, c := range e.Children {
+		if c != nil {
+			res.Children = append(res.Children, c.toElement(res))
+		}
+	}
+	return res
+}
+
+func (t *StringTable) toJson() stringTableJson {
+	return stringTableJson{
+		IsUtf8:        t.isUtf8,
+		StringOffsets: t.stringOffsets,
+		Data:          t.data,
+		Empty:         t.isEmpty(),
+	}
+}
+
+func (t *stringTableJson) toTable() (StringTable, error) {
+	res := StringTable{
+		isUtf8:        t.IsUtf8,
+		stringOffsets: t.StringOffsets,
+		data:          t.Data,
+	}
+
+	// StringTable.get reads the offsets as uint32 directly
+	if len(res.stringOffsets)%4 != 0 {
+		return res, fmt.Errorf("Invalid string table offsets length %d", len(res.stringOffsets))
+	}
+
+	if !t.Empty {
+		res.cache = make(map[uint32]string)
+	}
+	return res, nil
+}
+
+func (x *ResourceTable) toJson() *resourceTableJson {
+	res := &resourceTableJson{
+		MainStrings:   x.mainStrings.toJson(),
+		NextPackageId: x.nextPackageId,
+	}
+
+	// Sorted, so the same table always gives the same JSON
+	var groupIds []int
+	for id := range x.packages {
+		groupIds = append(groupIds, int(id))
+	}
+	sort.Ints(groupIds)
+
+	for _, id := range groupIds {
+		group := x.packages[uint32(id)]
+		g := packageGroupJson{
+			Name:          group.Name,
+			Id:            group.Id,
+			LargestTypeId: group.largestTypeId,
+		}
+
+		packageIdx := make(map[*resourcePackage]int)
+		for i, pkg := range group.Packages {
+			packageIdx[pkg] = i
+			g.Packages = append(g.Packages, resourcePackageJson{
+				Id:           pkg.Id,
+				Name:         pkg.Name,
+				TypeIdOffset: pkg.typeIdOffset,
+				TypeStrings:  pkg.typeStrings.toJson(),
+				KeyStrings:   pkg.keyStrings.toJson(),
+			})
+		}
+
+		var typeIds []int
+		for id := range group.types {
+			typeIds = append(typeIds, int(id))
+		}
+		sort.Ints(typeIds)
+
+		for _, typeId := range typeIds {
+			for _, spec := range group.types[uint8(typeId)] {
+				s := resourceTypeSpecJson{
+					Id:      spec.Id,
+					Entries: spec.Entries,
+					Package: packageIdx[spec.Package],
+				}
+				for _, typ := range spec.Configs {
+					s.Configs = append(s.Configs, resourceTypeJson{
+						ChunkData:    typ.chunkData,
+						EntryCount:   typ.entryCount,
+						EntriesStart: typ.entriesStart,
+						IndexesStart: typ.indexesStart,
+						Density:      typ.config.Density,
+						Language:     typ.config.Language,
+						Country:      typ.config.Country,
+					})
+				}
+				g.Types = append(g.Types, s)
+			}
+		}
+		res.Packages = append(res.Packages, g)
+	}
+	return res
+}
+
+func (js *resourceTableJson) toTable() (*ResourceTable, error) {
+	var err error
+	res := &ResourceTable{
+		nextPackageId: js.NextPackageId,
+		packages:      make(map[uint32]*packageGroup),
+	}
+
+	if res.mainStrings, err = js.MainStrings.toTable(); err != nil {
+		return nil, err
+	}
+
+	for _, g := range js.Packages {
+		group := &packageGroup{
+			Name:          g.Name,
+			Id:            g.Id,
+			table:         res,
+			largestTypeId: g.LargestTypeId,
+			types:         make(map[uint8][]resourceTypeSpec),
+		}
+
+		for _, p := range g.Packages {
+			pkg := &resourcePackage{
+				Id:           p.Id,
+				Name:         p.Name,
+				typeIdOffset: p.TypeIdOffset,
+			}
+			if pkg.typeStrings, err = p.TypeStrings.toTable(); err != nil {
+				return nil, err
+			}
+			if pkg.keyStrings, err = p.KeyStrings.toTable(); err != nil {
+				return nil, err
+			}
+			group.Packages = append(group.Packages, pkg)
+		}
+
+		for _, s := range g.Types {
+			if s.Package < 0 || s.Package >= len(group.Packages) {
+				return nil, fmt.Errorf("Invalid package index %d of type %d", s.Package, s.Id)
+			}
+
+			spec := resourceTypeSpec{
+				Id:      s.Id,
+				Entries: s.Entries,
+				Package: group.Packages[s.Package],
+			}
+			for _, t := range s.Configs {
+				if uint64(t.EntriesStart) > uint64(len(t.ChunkData)) || uint64(t.IndexesStart)+4*uint64(t.EntryCount) > uint64(len(t.ChunkData)) {
+					return nil, fmt.Errorf("Invalid config of type %d, out of bounds", s.Id)
+				}
+
+				spec.Configs = append(spec.Configs, &resourceType{
+					chunkData:    t.ChunkData,
+					entryCount:   t.EntryCount,
+					entriesStart: t.EntriesStart,
+					indexesStart: t.IndexesStart,
+					config:       ResTableConfig{Density: t.Density, Language: t.Language, Country: t.Country},
+				})
+			}
+			group.types[s.Id] = append(group.types[s.Id], spec)
+		}
+		res.packages[group.Id] = group
+	}
+	return res, nil
+}
@@ -11,13 +11,19 @@ import (
 )
 
 type manifestParseInfo struct {
-	strings     stringTable
+	strings     StringTable
 	resourceIds []uint32
 
 	encoder ManifestEncoder
 	res     *ResourceTable
 }
 
+// Implemented by encoders which also want the type and raw data of the attributes, in the same
+// order as they are in the following StartElement token.
+type rawAttrsEncoder interface {
+	encodeRawAttrs(types []AttrType, data []uint32)
+}
+
 // Parse the AndroidManifest.xml binary format. The resources are optional and can be nil.
 func ParseManifest(r io.Reader, enc ManifestEncoder, resources *ResourceTable) error {
 	x := manifestParseInfo{
@@ -95,6 +101,41 @@ func ParseManifest(r io.Reader, enc ManifestEncoder, resources *ResourceTable) e
 	return x.encoder.Flush()
 }
 
+// Returns the string pool of the binary XML, without decoding the rest of it.
+func parseXmlStringPool(r io.Reader) (*StringTable, error) {
+	_, _, totalLen, err := parseChunkHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	totalLen -= chunkHeaderSize
+
+	var len uint32
+	for i := uint32(0); i < totalLen; i += len {
+		var id uint16
+		id, _, len, err = parseChunkHeader(r)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing header at 0x%08x of 0x%08x: %s", i, totalLen, err.Error())
+		} else if len < chunkHeaderSize {
+			return nil, fmt.Errorf("Invalid chunk 0x%04x length %d", id, len)
+		}
+
+		lm := &io.LimitedReader{R: r, N: int64(len) - 2*4}
+		if id == chunkStringTable {
+			res, err := parseStringTable(lm)
+			if err != nil {
+				return nil, err
+			}
+			return &res, nil
+		}
+
+		if _, err = io.Copy(ioutil.Discard, lm); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("No string pool found.")
+}
+
 func (x *manifestParseInfo) parseResourceIds(r *io.LimitedReader) error {
 	if (r.N % 4) != 0 {
 		return fmt.Errorf("Invalid chunk size!")
@@ -193,6 +234,8 @@ func (x *manifestParseInfo) parseTagStart(r *io.LimitedReader) error {
 	}
 
 	var attrData [attrValuesCount]uint32
+	var rawTypes []AttrType
+	var rawData []uint32
 	for i := uint32(0); i < attrCnt; i++ {
 		if err := binary.Read(r, binary.LittleEndian, &attrData); err != nil {
 			return fmt.Errorf("error reading attrData: %s", err.Error())
@@ -264,8 +307,13 @@ func (x *manifestParseInfo) parseTagStart(r *io.LimitedReader) error {
 			attr.Value = strconv.FormatInt(int64(int32(attrData[attrIdxData])), 10)
 		}
 		tok.Attr = append(tok.Attr, attr)
+		rawTypes = append(rawTypes, AttrType(attrData[attrIdxType]>>24))
+		rawData = append(rawData, attrData[attrIdxData])
 	}
 
+	if raw, ok := x.encoder.(rawAttrsEncoder); ok {
+		raw.encodeRawAttrs(rawTypes, rawData)
+	}
 	return x.encoder.EncodeToken(tok)
 }
 
@@ -0,0 +1,110 @@
+package apkparser
+
+import "unsafe"
+
+// Approximate memory held by an APK, see APK.MemoryStats.
+type MemStats struct {
+	// Raw data of the string pools of resources.arsc.
+	StringTableBytes int64
+	// Raw type chunks of resources.arsc, entries are decoded from them on each lookup.
+	ResourceTableBytes int64
+	// The decoded AndroidManifest.xml element tree.
+	ManifestBytes int64
+	// Data loaded lazily by methods: decoded strings cached by the string pools and parsed dex files.
+	CacheBytes int64
+
+	// Peak of the sum of the above, or of the memory used while OpenAPK/NewAPK parsed the manifest
+	// and resources.arsc, whichever is higher. The parse peak is an upper bound: what was kept after
+	// the parse plus the decompressed sizes of the files, which the parsers buffer chunk by chunk.
+	PeakBytes int64
+}
+
+func (s MemStats) total() int64 {
+	return s.StringTableBytes + s.ResourceTableBytes + s.ManifestBytes + s.CacheBytes
+}
+
+// Returns the sizes of data the APK keeps in memory and the peak memory used, see MemStats.PeakBytes.
+// These are counted from lengths of the buffers, strings and slices, Go's allocation overhead is not included.
+// Everything parsed is kept until the APK is garbage collected, so the values only grow, e.g. CacheBytes
+// after the first call of a method which needs the dex files.
+func (a *APK) MemoryStats() MemStats {
+	var res MemStats
+	if a.resources != nil {
+		// Type chunks are slices of their package's block, which stays in memory as a whole.
+		// Count each backing array once, by its end.
+		blocks := make(map[*byte]int64)
+		addBlock := func(data []byte) {
+			if cap(data) == 0 {
+				return
+			}
+			end := &data[:cap(data)][cap(data)-1]
+			if int64(cap(data)) > blocks[end] {
+				blocks[end] = int64(cap(data))
+			}
+		}
+
+		tables := []*StringTable{&a.resources.mainStrings}
+		for _, group := range a.resources.packages {
+			for _, pkg := range group.Packages {
+				tables = append(tables, &pkg.typeStrings, &pkg.keyStrings)
+			}
+
+			for _, specs := range group.types {
+				for _, spec := range specs {
+					res.ResourceTableBytes += 4 * int64(len(spec.Entries))
+					for _, typ := range spec.Configs {
+						addBlock(typ.chunkData)
+					}
+				}
+			}
+		}
+
+		for _, size := range blocks {
+			res.ResourceTableBytes += size
+		}
+
+		for _, t := range tables {
+			res.StringTableBytes += int64(len(t.stringOffsets) + len(t.data))
+			for _, str := range t.cache {
+				res.CacheBytes += int64(len(str))
+			}
+		}
+	}
+
+	if a.manifest != nil {
+		res.ManifestBytes = a.manifest.memorySize()
+	}
+
+	for _, d := range a.dex {
+		res.CacheBytes += int64(len(d.data))
+		for _, str := range d.strings {
+			res.CacheBytes += int64(len(str))
+		}
+		// d.types are the same strings as in d.strings.
+		res.CacheBytes += int64(len(d.classes))*int64(unsafe.Sizeof(dexClassDef{})) +
+			int64(len(d.methods))*int64(unsafe.Sizeof(dexMethodId{})) +
+			int64(len(d.code))*int64(unsafe.Sizeof(dexMethodCode{}))
+		for i := range d.code {
+			res.CacheBytes += 4 * int64(len(d.code[i].Strings)+len(d.code[i].Invokes)+len(d.code[i].Ints))
+		}
+	}
+
+	res.PeakBytes = res.total()
+	if a.parsePeak > res.PeakBytes {
+		res.PeakBytes = a.parsePeak
+	}
+	return res
+}
+
+// Returns the length of names, attributes and texts of the element and its children.
+func (e *xmlElement) memorySize() int64 {
+	res := int64(len(e.Name) + len(e.Text))
+	for _, attr := range e.Attrs {
+		res += int64(len(attr.Name.Space) + len(attr.Name.Local) + len(attr.Value))
+	}
+	res += int64(len(e.attrTypes)) + 4*int64(len(e.attrData))
+	for _, c := range e.Children {
+		res += c.memorySize()
+	}
+	return res
+}
@@ -0,0 +1,47 @@
+package apkparser
+
+import (
+	"testing"
+)
+
+func TestMemoryStats(t *testing.T) {
+	var configs []testResConfig
+	for _, lang := range []string{"", "de", "fr", "ru"} {
+		configs = append(configs, testResConfig{language: lang, entries: []*testResEntry{
+			{key: "a", dataType: AttrTypeIntDec, data: 1},
+			{key: "b", dataType: AttrTypeIntDec, data: 2},
+		}})
+	}
+
+	arsc := buildArsc(nil, "com.example", []testResType{{name: "integer", configs: configs}, {name: "bool", configs: configs}})
+	manifest := testManifest()
+	a := openTestAPK(t, map[string][]byte{"AndroidManifest.xml": manifest, "resources.arsc": arsc})
+
+	stats := a.MemoryStats()
+	if stats.ResourceTableBytes == 0 || stats.ResourceTableBytes > int64(len(arsc)) {
+		t.Errorf("ResourceTableBytes %d of %d bytes resources.arsc", stats.ResourceTableBytes, len(arsc))
+	}
+	if stats.ManifestBytes == 0 {
+		t.Error("ManifestBytes is 0")
+	}
+
+	// Both files were read whole during the parse.
+	if expected := stats.total() + int64(len(arsc)+len(manifest)); stats.PeakBytes != expected {
+		t.Errorf("PeakBytes %d, expected %d", stats.PeakBytes, expected)
+	}
+
+	// The clone copies each type chunk on its own.
+	c, err := a.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := c.MemoryStats(); cs.ResourceTableBytes > stats.ResourceTableBytes {
+		t.Errorf("Clone has ResourceTableBytes %d, original %d", cs.ResourceTableBytes, stats.ResourceTableBytes)
+	}
+
+	d := dexAPK(t, []string{"Lcom/example/Main;", "Ljava/lang/Object;"}, []string{"Lcom/example/Main;", "Ljava/lang/Object;"}, nil, nil)
+	expected := int64(len(d.dex[0].data) + len("Lcom/example/Main;") + len("Ljava/lang/Object;"))
+	if stats := d.MemoryStats(); stats.CacheBytes != expected || stats.PeakBytes != expected {
+		t.Errorf("CacheBytes %d, PeakBytes %d, expected %d", stats.CacheBytes, stats.PeakBytes, expected)
+	}
+}
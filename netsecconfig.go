@@ -0,0 +1,134 @@
+package apkparser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Returns the parsed network security config XML referenced by android:networkSecurityConfig
+// in <application>, or nil if the app doesn't have one.
+func (a *APK) networkSecurityConfig() (*xmlElement, error) {
+	app := a.manifest.child("application")
+	if app == nil {
+		return nil, nil
+	}
+
+	path, prs := app.attr("networkSecurityConfig")
+	if !prs {
+		return nil, nil
+	}
+
+	// Unresolved reference, most likely resources.arsc is missing. Try the usual name.
+	if strings.HasPrefix(path, "@") {
+		path = "res/xml/network_security_config.xml"
+	}
+
+	if a.zip.File[path] == nil {
+		return nil, nil
+	}
+	return a.parseXml(path)
+}
+
+// Returns true if the app can't use cleartext HTTP: the network security config's <base-config>
+// doesn't permit cleartext traffic and no <domain-config> permits it either. Without attribute
+// or config, the default is used, which is no cleartext for apps targeting SDK 28+. Apps without
+// network security config use android:usesCleartextTraffic from the manifest.
+func (a *APK) HTTPSTrafficOnly() (bool, error) {
+	cleartextDefault := a.targetSdkVersion() < 28
+
+	nsc, err := a.networkSecurityConfig()
+	if err != nil {
+		return false, err
+	} else if nsc == nil {
+		return !a.applicationBoolAttr("usesCleartextTraffic", cleartextDefault), nil
+	}
+
+	cleartext := cleartextDefault
+	if base := nsc.child("base-config"); base != nil {
+		if val, prs := base.attr("cleartextTrafficPermitted"); prs {
+			cleartext = val == "true"
+		}
+	}
+
+	for _, dc := range nsc.findAll("domain-config") {
+		if dc.attrOrEmpty("cleartextTrafficPermitted") == "true" {
+			cleartext = true
+		}
+	}
+	return !cleartext, nil
+}
+
+// Returns true if the network security config's <base-config> requires Certificate Transparency
+// (SDK 35+), through <certificateTransparency> (or <certificate-transparency>) element which
+// isn't enabled="false". Returns false for apps without the element.
+func (a *APK) CertificateTransparencyRequired() (bool, error) {
+	nsc, err := a.networkSecurityConfig()
+	if err != nil || nsc == nil {
+		return false, err
+	}
+
+	base := nsc.child("base-config")
+	if base == nil {
+		return false, nil
+	}
+
+	for _, name := range []string{"certificateTransparency", "certificate-transparency"} {
+		if ct := base.child(name); ct != nil {
+			return ct.attrOrEmpty("enabled") != "false" && ct.attrOrEmpty("required") != "false", nil
+		}
+	}
+	return false, nil
+}
+
+// Returns the expiration of the <pin-set> which applies to the domain in the network security config,
+// that is of the most specific <domain-config> matching the domain, or its parent. The bool is false
+// if no pin set applies to the domain. The time is zero for pin sets without expiration.
+//
+// Android stops enforcing the pins after the pin set's expiration date.
+func (a *APK) PinSetExpiry(domain string) (time.Time, bool, error) {
+	nsc, err := a.networkSecurityConfig()
+	if err != nil || nsc == nil {
+		return time.Time{}, false, err
+	}
+
+	domain = strings.ToLower(domain)
+	var best *xmlElement
+	bestLen := -1
+	for _, dc := range nsc.findAll("domain-config") {
+		for _, d := range dc.children("domain") {
+			name := strings.ToLower(strings.TrimSpace(d.Text))
+			matches := name == domain ||
+				(d.attrOrEmpty("includeSubdomains") == "true" && strings.HasSuffix(domain, "."+name))
+
+			// Exact match wins over subdomains of a longer domain.
+			length := len(name)
+			if name == domain {
+				length = len(domain) + 1
+			}
+
+			if matches && length > bestLen {
+				best, bestLen = dc, length
+			}
+		}
+	}
+
+	for dc := best; dc != nil && dc.Name == "domain-config"; dc = dc.parent {
+		pinSet := dc.child("pin-set")
+		if pinSet == nil {
+			continue
+		}
+
+		expiration, prs := pinSet.attr("expiration")
+		if !prs {
+			return time.Time{}, true, nil
+		}
+
+		t, err := time.Parse("2006-01-02", strings.TrimSpace(expiration))
+		if err != nil {
+			return time.Time{}, true, fmt.Errorf("Invalid pin-set expiration '%s': %s", expiration, err.Error())
+		}
+		return t, true, nil
+	}
+	return time.Time{}, false, nil
+}
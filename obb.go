@@ -0,0 +1,56 @@
+package apkparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const obbMetaDataPrefix = "com.android.vending.expansion."
+
+// APK expansion files declared in the manifest meta-data. Values which are not declared are 0.
+type OBBInfo struct {
+	MainVersion    int
+	PatchVersion   int
+	MainSizeBytes  int64
+	PatchSizeBytes int64
+}
+
+// Returns the expansion file declarations from com.android.vending.expansion.* meta-data,
+// or empty slice if there are none.
+func (a *APK) OBBFiles() ([]OBBInfo, error) {
+	var info OBBInfo
+	found := false
+
+	for _, v := range []struct {
+		name   string
+		target interface{}
+	}{
+		{"mainFileVersion", &info.MainVersion},
+		{"patchFileVersion", &info.PatchVersion},
+		{"mainFileSize", &info.MainSizeBytes},
+		{"patchFileSize", &info.PatchSizeBytes},
+	} {
+		val, prs := a.metaData(obbMetaDataPrefix + v.name)
+		if !prs {
+			continue
+		}
+
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s%s value '%s': %s", obbMetaDataPrefix, v.name, val, err.Error())
+		}
+
+		switch t := v.target.(type) {
+		case *int:
+			*t = int(n)
+		case *int64:
+			*t = n
+		}
+		found = true
+	}
+
+	if !found {
+		return []OBBInfo{}, nil
+	}
+	return []OBBInfo{info}, nil
+}
@@ -0,0 +1,41 @@
+package apkparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Parses the (text) XML privileged permission whitelist from /etc/permissions/privapp-permissions-*.xml
+// on the system image. Returns map of package name to the permissions granted to it,
+// <deny-permission> entries are not included.
+func ParsePrivAppPermissions(r io.Reader) (map[string][]string, error) {
+	var doc struct {
+		Packages []struct {
+			Package     string `xml:"package,attr"`
+			Permissions []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"permission"`
+		} `xml:"privapp-permissions"`
+	}
+
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Failed to parse privapp permissions: %s", err.Error())
+	}
+
+	res := make(map[string][]string)
+	for _, pkg := range doc.Packages {
+		if pkg.Package == "" {
+			continue
+		}
+
+		perms := res[pkg.Package]
+		for _, p := range pkg.Permissions {
+			if p.Name != "" {
+				perms = append(perms, p.Name)
+			}
+		}
+		res[pkg.Package] = perms
+	}
+	return res, nil
+}
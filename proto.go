@@ -0,0 +1,158 @@
+package apkparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/avast/apkparser/apkpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Serializes the APK as APKSummary message from apkpb/apk.proto: basic info, the whole manifest
+// and a summary of resources (types and their entry counts, not the values).
+func (a *APK) MarshalProto() ([]byte, error) {
+	if a.manifest == nil {
+		return nil, errNoManifest
+	}
+	return proto.Marshal(a.protoSummary())
+}
+
+func (a *APK) protoSummary() *apkpb.APKSummary {
+	res := &apkpb.APKSummary{
+		PackageName:      a.packageName(),
+		VersionName:      a.manifest.attrOrEmpty("versionName"),
+		TargetSdkVersion: int32(a.targetSdkVersion()),
+		Manifest:         &apkpb.Manifest{Root: a.manifest.toProto()},
+	}
+
+	if v, err := strconv.ParseInt(a.manifest.attrOrEmpty("versionCode"), 0, 32); err == nil {
+		res.VersionCode = int32(v)
+	}
+
+	if usesSdk := a.manifest.child("uses-sdk"); usesSdk != nil {
+		if v, err := strconv.ParseInt(usesSdk.attrOrEmpty("minSdkVersion"), 0, 32); err == nil {
+			res.MinSdkVersion = int32(v)
+		}
+	}
+
+	if a.resources != nil {
+		res.Resources = a.resources.protoSummary()
+	}
+	return res
+}
+
+// Loads the manifest from APKSummary message made by MarshalProto. The resource summary can't
+// be turned back into a resource table, so the APK behaves like one without resources.arsc.
+// As with UnmarshalJSON, the APK has no zip file and doesn't have to be closed.
+func (a *APK) UnmarshalProto(data []byte) error {
+	var summary apkpb.APKSummary
+	if err := proto.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("Invalid APK proto: %s", err.Error())
+	}
+
+	root := summary.GetManifest().GetRoot()
+	if root.GetName() != "manifest" {
+		return fmt.Errorf("Invalid APK proto, missing manifest.")
+	}
+
+	*a = APK{
+		zip: &ZipReader{
+			File: make(map[string]*ZipReaderFile),
+		},
+		resourcesErr: os.ErrNotExist,
+		manifest:     xmlElementFromProto(root, nil),
+	}
+	return nil
+}
+
+func (e *xmlElement) toProto() *apkpb.Element {
+	res := &apkpb.Element{
+		Name: e.Name,
+		Text: e.Text,
+	}
+
+	for i, attr := range e.Attrs {
+		msg := &apkpb.Attribute{
+			Namespace: attr.Name.Space,
+			Name:      attr.Name.Local,
+			Value:     attr.Value,
+		}
+		if e.attrTypes != nil {
+			typ := uint32(e.attrTypes[i])
+			msg.Type, msg.Data = &typ, e.attrData[i]
+		}
+		res.Attributes = append(res.Attributes, msg)
+	}
+
+	for _, c := range e.Children {
+		res.Children = append(res.Children, c.toProto())
+	}
+	return res
+}
+
+func xmlElementFromProto(msg *apkpb.Element, parent *xmlElement) *xmlElement {
+	el := &xmlElement{
+		Name:   msg.GetName(),
+		Text:   msg.GetText(),
+		parent: parent,
+	}
+
+	raw := len(msg.GetAttributes()) != 0
+	for _, attr := range msg.GetAttributes() {
+		el.Attrs = append(el.Attrs, xml.Attr{
+			Name:  xml.Name{Space: attr.GetNamespace(), Local: attr.GetName()},
+			Value: attr.GetValue(),
+		})
+		raw = raw && attr.Type != nil
+	}
+	if raw {
+		for _, attr := range msg.GetAttributes() {
+			el.attrTypes = append(el.attrTypes, AttrType(attr.GetType()))
+			el.attrData = append(el.attrData, attr.GetData())
+		}
+	}
+
+	for _, c := range msg.GetChildren() {
+		el.Children = append(el.Children, xmlElementFromProto(c, el))
+	}
+	return el
+}
+
+func (x *ResourceTable) protoSummary() *apkpb.ResourceTableSummary {
+	var groupIds []int
+	for id := range x.packages {
+		groupIds = append(groupIds, int(id))
+	}
+	sort.Ints(groupIds)
+
+	res := &apkpb.ResourceTableSummary{}
+	for _, id := range groupIds {
+		group := x.packages[uint32(id)]
+
+		var typeIds []int
+		for typeId := range group.types {
+			typeIds = append(typeIds, int(typeId))
+		}
+		sort.Ints(typeIds)
+
+		pkg := &apkpb.ResourcePackage{
+			Id:   group.Id,
+			Name: group.Name,
+		}
+		for _, typeId := range typeIds {
+			for _, spec := range group.types[uint8(typeId)] {
+				name, _ := spec.Package.typeStrings.get(uint32(typeId) - 1 - spec.Package.typeIdOffset)
+				pkg.Types = append(pkg.Types, &apkpb.ResourceType{
+					Name:        name,
+					EntryCount:  uint32(len(spec.Entries)),
+					ConfigCount: uint32(len(spec.Configs)),
+				})
+			}
+		}
+		res.Packages = append(res.Packages, pkg)
+	}
+	return res
+}
@@ -0,0 +1,130 @@
+package apkparser
+
+import (
+	"fmt"
+)
+
+// Access control of one content provider, see APK.ContentProviderPermissions.
+type ProviderPermission struct {
+	// Value of android:authorities, multiple authorities are separated by ';'.
+	Authority       string
+	ReadPermission  string
+	WritePermission string
+	Exported        bool
+
+	GrantUriPermissions bool
+	PathPermissions     []PathPermission
+}
+
+// One <path-permission> of a provider. Only one of Path, PathPrefix and PathPattern is usually set.
+type PathPermission struct {
+	Path            string
+	PathPrefix      string
+	PathPattern     string
+	ReadPermission  string
+	WritePermission string
+}
+
+// Returns the permissions protecting each <provider>. android:permission (of the provider
+// or the application) is used for read and write permission if the specific one is not set, like Android does. Exported provider
+// without ReadPermission can be read by any app.
+func (a *APK) ContentProviderPermissions() ([]ProviderPermission, error) {
+	var res []ProviderPermission
+	for _, p := range a.components("provider") {
+		read, write := readWritePermissions(p, a.componentPermission(p))
+		perm := ProviderPermission{
+			Authority:           p.attrOrEmpty("authorities"),
+			ReadPermission:      read,
+			WritePermission:     write,
+			Exported:            a.isExported(p),
+			GrantUriPermissions: p.attrOrEmpty("grantUriPermissions") == "true",
+		}
+
+		for _, pp := range p.children("path-permission") {
+			read, write := readWritePermissions(pp, pp.attrOrEmpty("permission"))
+			perm.PathPermissions = append(perm.PathPermissions, PathPermission{
+				Path:            pp.attrOrEmpty("path"),
+				PathPrefix:      pp.attrOrEmpty("pathPrefix"),
+				PathPattern:     pp.attrOrEmpty("pathPattern"),
+				ReadPermission:  read,
+				WritePermission: write,
+			})
+		}
+		res = append(res, perm)
+	}
+	return res, nil
+}
+
+// Returns android:readPermission and android:writePermission, falling back to perm.
+func readWritePermissions(el *xmlElement, perm string) (read, write string) {
+	read, write = perm, perm
+	if val, prs := el.attr("readPermission"); prs {
+		read = val
+	}
+	if val, prs := el.attr("writePermission"); prs {
+		write = val
+	}
+	return
+}
+
+// One path shared by a FileProvider, see APK.FileProviderPaths.
+type FileProviderPath struct {
+	// Authorities of the provider which shares this path.
+	Authority string
+	// Element name, e.g. "files-path", "external-path" or "root-path".
+	Type string
+	Name string
+	Path string
+}
+
+// Returns the paths shared by FileProviders, from the XML referenced by their
+// android.support.FILE_PROVIDER_PATHS meta-data. root-path or path="." shares far
+// more than the app likely wants to.
+//
+// Fails if the XML of any provider can't be found (e.g. with broken resources.arsc),
+// rather than returning incomplete paths.
+func (a *APK) FileProviderPaths() ([]FileProviderPath, error) {
+	var res []FileProviderPath
+	for _, p := range a.components("provider") {
+		ref, prs := elementMetaData(p, "android.support.FILE_PROVIDER_PATHS")
+		if !prs {
+			continue
+		}
+
+		xmlPath, err := a.referencedFilePath(ref)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find paths XML '%s' of provider %s: %s", ref, a.componentName(p), err.Error())
+		}
+
+		paths, err := a.parseXml(xmlPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, el := range paths.Children {
+			res = append(res, FileProviderPath{
+				Authority: p.attrOrEmpty("authorities"),
+				Type:      el.Name,
+				Name:      el.attrOrEmpty("name"),
+				Path:      el.attrOrEmpty("path"),
+			})
+		}
+	}
+	return res, nil
+}
+
+// Returns names of exported providers with neither read nor write permission, which don't
+// grant URI permissions either. Any app can read and write their data.
+func (a *APK) ExportedProvidersWithoutPermission() ([]string, error) {
+	var res []string
+	for _, p := range a.components("provider") {
+		if !a.isExported(p) || p.attrOrEmpty("grantUriPermissions") == "true" {
+			continue
+		}
+
+		if read, write := readWritePermissions(p, a.componentPermission(p)); read == "" && write == "" {
+			res = append(res, a.componentName(p))
+		}
+	}
+	return res, nil
+}
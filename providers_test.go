@@ -0,0 +1,60 @@
+package apkparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileProviderPaths(t *testing.T) {
+	paths := buildAxml(&testXmlElement{name: "paths", children: []*testXmlElement{
+		{name: "files-path", attrs: []testXmlAttr{testStringAttr("name", "files"), testStringAttr("path", "images/")}},
+		{name: "root-path", attrs: []testXmlAttr{testStringAttr("name", "root"), testStringAttr("path", ".")}},
+	}})
+
+	manifest := func(resource testXmlAttr) []byte {
+		resource.name = "resource"
+		return buildAxml(&testXmlElement{
+			name:  "manifest",
+			attrs: []testXmlAttr{testStringAttr("package", "com.example")},
+			children: []*testXmlElement{{name: "application", children: []*testXmlElement{
+				{name: "provider", attrs: []testXmlAttr{testStringAttr("name", "androidx.core.content.FileProvider"), testStringAttr("authorities", "com.example.files")},
+					children: []*testXmlElement{{name: "meta-data", attrs: []testXmlAttr{testStringAttr("name", "android.support.FILE_PROVIDER_PATHS"), resource}}}},
+				{name: "provider", attrs: []testXmlAttr{testStringAttr("name", ".Other"), testStringAttr("authorities", "com.example.other")}},
+			}}},
+		})
+	}
+
+	expected := []FileProviderPath{
+		{Authority: "com.example.files", Type: "files-path", Name: "files", Path: "images/"},
+		{Authority: "com.example.files", Type: "root-path", Name: "root", Path: "."},
+	}
+
+	tests := []struct {
+		name     string
+		resource testXmlAttr
+		error    string
+	}{
+		{"resolved path", testStringAttr("", "res/xml/file_paths.xml"), ""},
+		{"resource name", testStringAttr("", "@xml/file_paths"), ""},
+		{"unresolved reference", testXmlAttr{dataType: AttrTypeReference, data: 0x7f0a0001},
+			"Failed to find paths XML '@7f0a0001' of provider androidx.core.content.FileProvider"},
+		{"missing file", testStringAttr("", "res/xml/a.xml"), "Failed to find paths XML 'res/xml/a.xml'"},
+	}
+
+	for _, test := range tests {
+		a := openTestAPK(t, map[string][]byte{
+			"AndroidManifest.xml":    manifest(test.resource),
+			"res/xml/file_paths.xml": paths,
+		})
+
+		res, err := a.FileProviderPaths()
+		if test.error != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.error) {
+				t.Errorf("%s: expected error '%s', got %v %v", test.name, test.error, res, err)
+			}
+		} else if err != nil || !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s: got %v %v", test.name, res, err)
+		}
+	}
+}
@@ -8,15 +8,18 @@ import (
 	"io"
 	"io/ioutil"
 	"math"
+	"sort"
 	"strings"
 	"unicode/utf16"
+
+	"golang.org/x/text/language"
 )
 
 var ErrUnknownResourceDataType = errors.New("Unknown resource data type")
 
 // Contains parsed resources.arsc file.
 type ResourceTable struct {
-	mainStrings   stringTable
+	mainStrings   StringTable
 	nextPackageId uint32
 	packages      map[uint32]*packageGroup
 }
@@ -36,8 +39,8 @@ type resourcePackage struct {
 	Name string
 
 	typeIdOffset uint32
-	typeStrings  stringTable
-	keyStrings   stringTable
+	typeStrings  StringTable
+	keyStrings   StringTable
 }
 
 type resourceTypeSpec struct {
@@ -54,9 +57,23 @@ type resourceType struct {
 	entriesStart uint32
 	indexesStart uint32
 
-	// ResTable_config config;
+	config ResTableConfig
+}
+
+// The parts of ResTable_config (the configuration qualifiers like -cs-rCZ or -xhdpi) this library uses.
+type ResTableConfig struct {
+	// 0 for the default, 0xfffe for anydpi and 0xffff for nodpi.
+	Density uint16
+	// Unpacked, e.g. "en" and "US". Empty for the default config.
+	Language string
+	Country  string
 }
 
+const (
+	densityAny  = 0xfffe
+	densityNone = 0xffff
+)
+
 const (
 	tableEntryComplex = 0x0001
 	tableEntryPublic  = 0x0002
@@ -73,6 +90,19 @@ type ResourceEntry struct {
 	Package      string
 
 	value ResourceValue
+
+	// Of complex entries. Android reads the maps only when they are used, so a malformed map
+	// doesn't fail the lookup of the entry, it is kept in mapErr.
+	parent     uint32
+	mapEntries []resourceMapEntry
+	mapErr     error
+}
+
+// One item of a complex entry, ResTable_map. The name is an attribute id for styles,
+// a quantity for plurals and index for arrays.
+type resourceMapEntry struct {
+	name  uint32
+	value ResourceValue
 }
 
 // Handle to the resource's actual value.
@@ -80,7 +110,7 @@ type ResourceValue struct {
 	dataType AttrType
 	data     uint32
 
-	globalStringTable *stringTable
+	globalStringTable *StringTable
 	convertedData     interface{}
 }
 
@@ -95,6 +125,58 @@ const (
 	ConfigLast                              // Usually the biggest
 )
 
+// Returns a deep copy of the table
+func (x *ResourceTable) clone() *ResourceTable {
+	res := &ResourceTable{
+		mainStrings:   x.mainStrings.clone(),
+		nextPackageId: x.nextPackageId,
+		packages:      make(map[uint32]*packageGroup, len(x.packages)),
+	}
+
+	for id, group := range x.packages {
+		g := &packageGroup{
+			Name:          group.Name,
+			Id:            group.Id,
+			table:         res,
+			largestTypeId: group.largestTypeId,
+			types:         make(map[uint8][]resourceTypeSpec, len(group.types)),
+		}
+
+		packages := make(map[*resourcePackage]*resourcePackage, len(group.Packages))
+		for _, pkg := range group.Packages {
+			p := &resourcePackage{
+				Id:           pkg.Id,
+				Name:         pkg.Name,
+				typeIdOffset: pkg.typeIdOffset,
+				typeStrings:  pkg.typeStrings.clone(),
+				keyStrings:   pkg.keyStrings.clone(),
+			}
+			packages[pkg] = p
+			g.Packages = append(g.Packages, p)
+		}
+
+		for typeId, specs := range group.types {
+			newSpecs := make([]resourceTypeSpec, len(specs))
+			for i, spec := range specs {
+				newSpecs[i] = resourceTypeSpec{
+					Id:      spec.Id,
+					Entries: append([]uint32(nil), spec.Entries...),
+					Package: packages[spec.Package],
+				}
+
+				for _, typ := range spec.Configs {
+					t := *typ
+					t.chunkData = append([]byte(nil), typ.chunkData...)
+					newSpecs[i].Configs = append(newSpecs[i].Configs, &t)
+				}
+			}
+			g.types[typeId] = newSpecs
+		}
+		res.packages[id] = g
+	}
+	return res
+}
+
 // Parses the resources.arsc file
 func ParseResourceTable(r io.Reader) (*ResourceTable, error) {
 	res := ResourceTable{
@@ -351,14 +433,17 @@ func (x *ResourceTable) parseType(r io.Reader, pkg *resourcePackage, group *pack
 
 		EntryCount   uint32
 		EntriesStart uint32
-
-		//ResTable_config config;
 	}{}
 
 	if err := binary.Read(r, binary.LittleEndian, &vals); err != nil {
 		return fmt.Errorf("error reading values: %s", err.Error())
 	}
 
+	config, err := parseResTableConfig(r, int64(hdrLen)-chunkHeaderSize-12)
+	if err != nil {
+		return fmt.Errorf("error reading config: %s", err.Error())
+	}
+
 	if vals.Id == 0 {
 		return fmt.Errorf("Invalid type id: %d", vals.Id)
 	}
@@ -375,11 +460,93 @@ func (x *ResourceTable) parseType(r io.Reader, pkg *resourcePackage, group *pack
 			entryCount:   vals.EntryCount,
 			entriesStart: vals.EntriesStart,
 			indexesStart: uint32(hdrLen),
+			config:       config,
 		})
 	}
 	return nil
 }
 
+// Reads the config from the type chunk, at most maxLen bytes of it. Android doesn't fail
+// on configs that don't fit the chunk header, so neither does this.
+func parseResTableConfig(r io.Reader, maxLen int64) (res ResTableConfig, err error) {
+	var size uint32
+	if maxLen < 4 {
+		return
+	}
+
+	if err = binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return
+	}
+
+	// The struct grew over time, older files have shorter configs.
+	dataLen := int64(size) - 4
+	if dataLen > maxLen-4 {
+		dataLen = maxLen - 4
+	}
+
+	if dataLen <= 0 {
+		return
+	}
+
+	data := make([]byte, dataLen)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return
+	}
+
+	// imsi (4), locale (4), screenType: orientation (1), touchscreen (1), density (2)
+	if len(data) >= 8 {
+		res.Language = unpackLocale(data[4:6], 'a')
+		res.Country = unpackLocale(data[6:8], '0')
+	}
+	if len(data) >= 12 {
+		res.Density = binary.LittleEndian.Uint16(data[10:])
+	}
+	return
+}
+
+// Unpacks the language or country code of ResTable_config. Two-letter codes are stored as they are,
+// three-letter ones are packed into 5 bits per letter, offset from base.
+func unpackLocale(in []byte, base byte) string {
+	if in[0]&0x80 != 0 {
+		first := in[1] & 0x1f
+		second := ((in[1] & 0xe0) >> 5) + ((in[0] & 0x03) << 3)
+		third := (in[0] & 0x7c) >> 2
+		return string([]byte{first + base, second + base, third + base})
+	} else if in[0] == 0 {
+		return ""
+	}
+	return string(in)
+}
+
+// Android still uses the deprecated codes for these languages.
+var legacyLanguageCodes = map[string]string{
+	"in": "id",
+	"iw": "he",
+	"ji": "yi",
+}
+
+// Returns how well the config matches the language and region: 0 for the default config,
+// 1 for the same language, 2 for the same language and region and -1 if it doesn't match.
+func (c *ResTableConfig) localeScore(lang, region string) int {
+	cfgLang := c.Language
+	if code, prs := legacyLanguageCodes[cfgLang]; prs {
+		cfgLang = code
+	}
+
+	switch {
+	case cfgLang == "":
+		return 0
+	case cfgLang != lang:
+		return -1
+	case c.Country == "":
+		return 1
+	case c.Country == region:
+		return 2
+	default:
+		return -1
+	}
+}
+
 // Converts the resource id to readable name including the package name like "@drawable:com.example.app.icon".
 func (x *ResourceTable) GetResourceName(resId uint32) (string, error) {
 	pkgId := (resId >> 24)
@@ -399,6 +566,35 @@ func (x *ResourceTable) GetResourceName(resId uint32) (string, error) {
 	return fmt.Sprintf("@%s:%s.%s", entry.ResourceType, group.Name, entry.Key), nil
 }
 
+// Returns the resource id of the entry with this type and name, e.g. ("string", "app_name").
+func (x *ResourceTable) GetResourceId(typeName, entryName string) (uint32, error) {
+	pkgIds := make([]int, 0, len(x.packages))
+	for id := range x.packages {
+		pkgIds = append(pkgIds, int(id))
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(pkgIds)))
+
+	for _, pkgId := range pkgIds {
+		group := x.packages[uint32(pkgId)]
+		for typeId, typeList := range group.types {
+			for _, spec := range typeList {
+				name, err := spec.Package.typeStrings.get(uint32(typeId) - 1 - spec.Package.typeIdOffset)
+				if err != nil || name != typeName {
+					continue
+				}
+
+				for entryId := range spec.Entries {
+					entry, err := x.getEntry(group, uint32(typeId)-1, uint32(entryId), ConfigFirst)
+					if err == nil && entry.Key == entryName {
+						return uint32(pkgId)<<24 | uint32(typeId)<<16 | uint32(entryId), nil
+					}
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("Resource %s/%s not found.", typeName, entryName)
+}
+
 // Returns the resource entry for resId and the first configuration option it finds.
 func (x *ResourceTable) GetResourceEntry(resId uint32) (*ResourceEntry, error) {
 	return x.GetResourceEntryEx(resId, ConfigFirst)
@@ -428,47 +624,127 @@ func (x *ResourceTable) getEntry(group *packageGroup, typeId, entry uint32, conf
 	var lastRes *ResourceEntry
 	for _, typ := range typeList {
 		for _, thisType := range typ.Configs {
-			if entry >= thisType.entryCount {
+			r, err := thisType.entryReader(entry)
+			if err != nil {
+				return nil, err
+			} else if r == nil {
 				continue
 			}
 
-			r := bytes.NewReader(thisType.chunkData)
-			if _, err := r.Seek(int64(thisType.indexesStart+entry*4), io.SeekStart); err != nil {
-				return nil, err
+			res, err := x.parseEntry(r, typ.Package, typeId)
+			if err != nil {
+				lastErr = err
+			} else if config == ConfigFirst {
+				return res, nil
+			} else {
+				lastRes = res
 			}
+		}
+	}
 
-			var thisOffset uint32
-			if err := binary.Read(r, binary.LittleEndian, &thisOffset); err != nil {
-				return nil, fmt.Errorf("Failed to read this type offset: %s", err.Error())
-			}
+	if lastRes != nil {
+		return lastRes, nil
+	} else if lastErr != nil {
+		return nil, lastErr
+	} else {
+		return nil, fmt.Errorf("No entry found.")
+	}
+}
 
-			if thisOffset == math.MaxUint32 {
-				continue
+// Calls fn for every entry of every config in the table, in package, type, config and entry order.
+// Complex entries like arrays or plurals are passed with an AttrTypeNull value.
+// Stops and returns the error if fn returns one.
+func (x *ResourceTable) WalkEntries(fn func(packageName, typeName, entryName string, config ResTableConfig, value ResourceValue) error) error {
+	pkgIds := make([]int, 0, len(x.packages))
+	for id := range x.packages {
+		pkgIds = append(pkgIds, int(id))
+	}
+	sort.Ints(pkgIds)
+
+	for _, pkgId := range pkgIds {
+		group := x.packages[uint32(pkgId)]
+		for typeId := 1; typeId <= int(group.largestTypeId); typeId++ {
+			for _, spec := range group.types[uint8(typeId)] {
+				for _, thisType := range spec.Configs {
+					for entryId := uint32(0); entryId < thisType.entryCount; entryId++ {
+						r, err := thisType.entryReader(entryId)
+						if err != nil {
+							return err
+						} else if r == nil {
+							continue
+						}
+
+						entry, err := x.parseEntry(r, spec.Package, uint32(typeId)-1)
+						if err != nil {
+							return fmt.Errorf("Failed to read entry 0x%08x: %s", uint32(pkgId)<<24|uint32(typeId)<<16|entryId, err.Error())
+						}
+
+						var value ResourceValue
+						if !entry.IsComplex() {
+							value = entry.value
+						}
+
+						if err := fn(entry.Package, entry.ResourceType, entry.Key, thisType.config, value); err != nil {
+							return err
+						}
+					}
+				}
 			}
+		}
+	}
+	return nil
+}
+
+// Returns the resource entry for resId from the config best matching the locale, like Android does
+// for the device's locale: language and region match, language match, default config. If none
+// of them has the entry, returns the first configuration option found.
+func (x *ResourceTable) getEntryForLocale(resId uint32, locale language.Tag) (*ResourceEntry, error) {
+	group := x.packages[resId>>24]
+	if group == nil {
+		return nil, fmt.Errorf("Invalid package identifier.")
+	}
+
+	typeId := ((resId >> 16) & 0xFF) - 1
+	typeList := group.types[uint8(typeId+1)]
+	if len(typeList) == 0 {
+		return nil, fmt.Errorf("Invalid type: %d", typeId)
+	}
 
-			offset := thisType.entriesStart + thisOffset
+	base, _ := locale.Base()
+	lang := base.String()
+	var country string
+	if region, conf := locale.Region(); conf == language.Exact {
+		country = region.String()
+	}
 
-			if int(offset) >= len(thisType.chunkData) || ((offset & 0x03) != 0) {
-				return nil, fmt.Errorf("Invalid entry 0x%04x offset: %d!", entry, offset)
+	var lastErr error
+	var res *ResourceEntry
+	bestScore := -2
+	for _, typ := range typeList {
+		for _, thisType := range typ.Configs {
+			score := thisType.config.localeScore(lang, country)
+			if score <= bestScore {
+				continue
 			}
 
-			if _, err := r.Seek(int64(offset), io.SeekStart); err != nil {
+			r, err := thisType.entryReader(resId & 0xFFFF)
+			if err != nil {
 				return nil, err
+			} else if r == nil {
+				continue
 			}
 
-			res, err := x.parseEntry(r, typ.Package, typeId)
+			e, err := x.parseEntry(r, typ.Package, typeId)
 			if err != nil {
 				lastErr = err
-			} else if config == ConfigFirst {
-				return res, nil
 			} else {
-				lastRes = res
+				res, bestScore = e, score
 			}
 		}
 	}
 
-	if lastRes != nil {
-		return lastRes, nil
+	if res != nil {
+		return res, nil
 	} else if lastErr != nil {
 		return nil, lastErr
 	} else {
@@ -476,6 +752,38 @@ func (x *ResourceTable) getEntry(group *packageGroup, typeId, entry uint32, conf
 	}
 }
 
+// Returns reader positioned at the entry, nil and no error if the config doesn't have it.
+func (t *resourceType) entryReader(entry uint32) (*bytes.Reader, error) {
+	if entry >= t.entryCount {
+		return nil, nil
+	}
+
+	r := bytes.NewReader(t.chunkData)
+	if _, err := r.Seek(int64(t.indexesStart+entry*4), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var thisOffset uint32
+	if err := binary.Read(r, binary.LittleEndian, &thisOffset); err != nil {
+		return nil, fmt.Errorf("Failed to read this type offset: %s", err.Error())
+	}
+
+	if thisOffset == math.MaxUint32 {
+		return nil, nil
+	}
+
+	offset := t.entriesStart + thisOffset
+
+	if int(offset) >= len(t.chunkData) || ((offset & 0x03) != 0) {
+		return nil, fmt.Errorf("Invalid entry 0x%04x offset: %d!", entry, offset)
+	}
+
+	if _, err := r.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 func (x *ResourceTable) parseEntry(r io.Reader, pkg *resourcePackage, typeId uint32) (*ResourceEntry, error) {
 	var err error
 	var res ResourceEntry
@@ -506,39 +814,72 @@ func (x *ResourceTable) parseEntry(r io.Reader, pkg *resourcePackage, typeId uin
 	}
 
 	if !res.IsComplex() {
-		var size uint16
-		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
-			return nil, fmt.Errorf("Failed to read entry value size: %s", err.Error())
+		if err := x.parseValue(r, &res.value); err != nil {
+			return nil, err
 		}
+	} else {
+		res.mapErr = x.parseMap(r, &res)
+	}
 
-		if size < 8 {
-			return nil, fmt.Errorf("Invalid Res_value size: %d!", size)
-		}
+	return &res, nil
+}
 
-		if _, err := io.CopyN(ioutil.Discard, r, 1); err != nil {
-			return nil, fmt.Errorf("Failed to read entry value res0: %s", err.Error())
-		}
+// Reads ResTable_map_entry after the key and its ResTable_map items.
+func (x *ResourceTable) parseMap(r io.Reader, res *ResourceEntry) error {
+	if err := binary.Read(r, binary.LittleEndian, &res.parent); err != nil {
+		return fmt.Errorf("Failed to read entry parent: %s", err.Error())
+	}
 
-		if err := binary.Read(r, binary.LittleEndian, &res.value.dataType); err != nil {
-			return nil, fmt.Errorf("Failed to read entry value data type: %s", err.Error())
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("Failed to read entry map count: %s", err.Error())
+	}
+
+	for i := uint32(0); i < count; i++ {
+		var m resourceMapEntry
+		if err := binary.Read(r, binary.LittleEndian, &m.name); err != nil {
+			return fmt.Errorf("Failed to read entry map name: %s", err.Error())
 		}
 
-		if err := binary.Read(r, binary.LittleEndian, &res.value.data); err != nil {
-			return nil, fmt.Errorf("Failed to read entry value data: %s", err.Error())
+		if err := x.parseValue(r, &m.value); err != nil {
+			return err
 		}
+		res.mapEntries = append(res.mapEntries, m)
+	}
+	return nil
+}
 
-		res.value.globalStringTable = &x.mainStrings
+// Reads Res_value
+func (x *ResourceTable) parseValue(r io.Reader, value *ResourceValue) error {
+	var size uint16
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return fmt.Errorf("Failed to read entry value size: %s", err.Error())
+	}
 
-	} else {
-		// NYI
+	if size < 8 {
+		return fmt.Errorf("Invalid Res_value size: %d!", size)
 	}
 
-	return &res, nil
+	if _, err := io.CopyN(ioutil.Discard, r, 1); err != nil {
+		return fmt.Errorf("Failed to read entry value res0: %s", err.Error())
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, &value.dataType); err != nil {
+		return fmt.Errorf("Failed to read entry value data type: %s", err.Error())
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, &value.data); err != nil {
+		return fmt.Errorf("Failed to read entry value data: %s", err.Error())
+	}
+
+	value.globalStringTable = &x.mainStrings
+	return nil
 }
 
 // Returns true if the resource entry is complex (for example arrays, string plural arrays...).
 //
-// Complex ResourceEntries are not yet supported.
+// Values of complex ResourceEntries are not accessible through GetValue, only through
+// methods like APK.PluralsResource.
 func (e *ResourceEntry) IsComplex() bool {
 	return (e.flags & tableEntryComplex) != 0
 }
@@ -0,0 +1,386 @@
+package apkparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/text/language"
+)
+
+type testResMapItem struct {
+	name     uint32
+	dataType uint8
+	data     uint32
+}
+
+type testResEntry struct {
+	key      string
+	dataType uint8
+	data     uint32
+	complex  []testResMapItem
+	parent   uint32
+	isMap    bool
+	// Claims one more map item than there is
+	truncated bool
+}
+
+type testResConfig struct {
+	density  uint16
+	language string
+	country  string
+	entries  []*testResEntry // index = entry id; nil = missing
+}
+
+type testResType struct {
+	name    string
+	configs []testResConfig
+}
+
+func buildStringPool(strs []string) []byte {
+	le := binary.LittleEndian
+	data := &bytes.Buffer{}
+	offs := []uint32{}
+	for _, s := range strs {
+		offs = append(offs, uint32(data.Len()))
+		n16 := len(utf16.Encode([]rune(s)))
+		if n16 > 0x7f {
+			data.WriteByte(byte(0x80 | n16>>8))
+			data.WriteByte(byte(n16))
+		} else {
+			data.WriteByte(byte(n16))
+		}
+		if len(s) > 0x7f {
+			data.WriteByte(byte(0x80 | len(s)>>8))
+			data.WriteByte(byte(len(s)))
+		} else {
+			data.WriteByte(byte(len(s)))
+		}
+		data.WriteString(s)
+		data.WriteByte(0)
+	}
+	for data.Len()%4 != 0 {
+		data.WriteByte(0)
+	}
+	out := &bytes.Buffer{}
+	total := 28 + 4*len(strs) + data.Len()
+	binary.Write(out, le, uint16(1))
+	binary.Write(out, le, uint16(28))
+	binary.Write(out, le, uint32(total))
+	binary.Write(out, le, uint32(len(strs)))
+	binary.Write(out, le, uint32(0))
+	binary.Write(out, le, uint32(0x100))
+	binary.Write(out, le, uint32(28+4*len(strs)))
+	binary.Write(out, le, uint32(0))
+	for _, o := range offs {
+		binary.Write(out, le, o)
+	}
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+// Builds resources.arsc with one package 0x7f named pkg. Type ids are indexes into types + 1,
+// entry ids indexes into the config entries, nil entries are missing in that config.
+func buildArsc(globalStrings []string, pkg string, types []testResType) []byte {
+	le := binary.LittleEndian
+	typeNames := []string{}
+	keys := []string{}
+	kidx := map[string]int{}
+	for _, t := range types {
+		typeNames = append(typeNames, t.name)
+		for _, c := range t.configs {
+			for _, e := range c.entries {
+				if e != nil {
+					if _, ok := kidx[e.key]; !ok {
+						kidx[e.key] = len(keys)
+						keys = append(keys, e.key)
+					}
+				}
+			}
+		}
+	}
+	typePool := buildStringPool(typeNames)
+	keyPool := buildStringPool(keys)
+	body := &bytes.Buffer{}
+	body.Write(typePool)
+	body.Write(keyPool)
+	for ti, t := range types {
+		n := 0
+		for _, c := range t.configs {
+			if len(c.entries) > n {
+				n = len(c.entries)
+			}
+		}
+		binary.Write(body, le, uint16(0x202))
+		binary.Write(body, le, uint16(16))
+		binary.Write(body, le, uint32(16+4*n))
+		body.Write([]byte{byte(ti + 1), 0, 0, 0})
+		binary.Write(body, le, uint32(n))
+		for i := 0; i < n; i++ {
+			binary.Write(body, le, uint32(0))
+		}
+		for _, c := range t.configs {
+			cfg := make([]byte, 64)
+			le.PutUint32(cfg, 64)
+			copy(cfg[8:10], c.language)
+			copy(cfg[10:12], c.country)
+			le.PutUint16(cfg[14:], c.density)
+			entries := &bytes.Buffer{}
+			offs := []uint32{}
+			for _, e := range c.entries {
+				if e == nil {
+					offs = append(offs, 0xffffffff)
+					continue
+				}
+				offs = append(offs, uint32(entries.Len()))
+				if e.isMap {
+					binary.Write(entries, le, uint16(16))
+					binary.Write(entries, le, uint16(1))
+					binary.Write(entries, le, uint32(kidx[e.key]))
+					binary.Write(entries, le, e.parent)
+					count := uint32(len(e.complex))
+					if e.truncated {
+						count++
+					}
+					binary.Write(entries, le, count)
+					for _, m := range e.complex {
+						binary.Write(entries, le, m.name)
+						binary.Write(entries, le, uint16(8))
+						entries.WriteByte(0)
+						entries.WriteByte(m.dataType)
+						binary.Write(entries, le, m.data)
+					}
+				} else {
+					binary.Write(entries, le, uint16(8))
+					binary.Write(entries, le, uint16(0))
+					binary.Write(entries, le, uint32(kidx[e.key]))
+					binary.Write(entries, le, uint16(8))
+					entries.WriteByte(0)
+					entries.WriteByte(e.dataType)
+					binary.Write(entries, le, e.data)
+				}
+			}
+			hdr := 20 + 64
+			start := hdr + 4*len(offs)
+			binary.Write(body, le, uint16(0x201))
+			binary.Write(body, le, uint16(hdr))
+			binary.Write(body, le, uint32(start+entries.Len()))
+			body.Write([]byte{byte(ti + 1), 0, 0, 0})
+			binary.Write(body, le, uint32(len(offs)))
+			binary.Write(body, le, uint32(start))
+			body.Write(cfg)
+			for _, o := range offs {
+				binary.Write(body, le, o)
+			}
+			body.Write(entries.Bytes())
+		}
+	}
+	pk := &bytes.Buffer{}
+	hdrLen := 288
+	binary.Write(pk, le, uint16(0x200))
+	binary.Write(pk, le, uint16(hdrLen))
+	binary.Write(pk, le, uint32(hdrLen+body.Len()))
+	binary.Write(pk, le, uint32(0x7f))
+	name := make([]uint16, 128)
+	copy(name, utf16.Encode([]rune(pkg)))
+	binary.Write(pk, le, name)
+	binary.Write(pk, le, uint32(hdrLen))
+	binary.Write(pk, le, uint32(len(typeNames)))
+	binary.Write(pk, le, uint32(hdrLen+len(typePool)))
+	binary.Write(pk, le, uint32(len(keys)))
+	binary.Write(pk, le, uint32(0))
+	pk.Write(body.Bytes())
+
+	gp := buildStringPool(globalStrings)
+	out := &bytes.Buffer{}
+	binary.Write(out, le, uint16(2))
+	binary.Write(out, le, uint16(12))
+	binary.Write(out, le, uint32(12+len(gp)+pk.Len()))
+	binary.Write(out, le, uint32(1))
+	out.Write(gp)
+	out.Write(pk.Bytes())
+	return out.Bytes()
+}
+
+func parseTestArsc(t *testing.T, types []testResType) *ResourceTable {
+	res, err := ParseResourceTable(bytes.NewReader(buildArsc([]string{"one", "other", "few", "many"}, "com.example", types)))
+	if err != nil {
+		t.Fatalf("Failed to parse resources: %s", err.Error())
+	}
+	return res
+}
+
+func TestUnpackLocale(t *testing.T) {
+	tests := []struct {
+		in   [2]byte
+		base byte
+		out  string
+	}{
+		{[2]byte{0, 0}, 'a', ""},
+		{[2]byte{'e', 'n'}, 'a', "en"},
+		{[2]byte{'U', 'S'}, '0', "US"},
+		{[2]byte{0xad, 0x05}, 'a', "fil"},
+		{[2]byte{0xa4, 0x24}, '0', "419"},
+	}
+
+	for _, tt := range tests {
+		if out := unpackLocale(tt.in[:], tt.base); out != tt.out {
+			t.Errorf("%v: got '%s', expected '%s'", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestLocaleScore(t *testing.T) {
+	tests := []struct {
+		language, country string
+		lang, region      string
+		score             int
+	}{
+		{"", "", "en", "US", 0},
+		{"en", "", "en", "US", 1},
+		{"en", "US", "en", "US", 2},
+		{"en", "GB", "en", "US", -1},
+		{"en", "US", "en", "", -1},
+		{"de", "", "en", "", -1},
+		{"in", "", "id", "", 1},
+		{"iw", "IL", "he", "IL", 2},
+	}
+
+	for _, tt := range tests {
+		c := ResTableConfig{Language: tt.language, Country: tt.country}
+		if score := c.localeScore(tt.lang, tt.region); score != tt.score {
+			t.Errorf("%s-%s for %s-%s: got %d, expected %d", tt.language, tt.country, tt.lang, tt.region, score, tt.score)
+		}
+	}
+}
+
+func TestGetEntryForLocale(t *testing.T) {
+	table := parseTestArsc(t, []testResType{{name: "string", configs: []testResConfig{
+		{entries: []*testResEntry{{key: "s", dataType: AttrTypeString, data: 0}}},
+		{language: "en", country: "GB", entries: []*testResEntry{{key: "s", dataType: AttrTypeString, data: 1}}},
+		{language: "de", entries: []*testResEntry{{key: "s", dataType: AttrTypeString, data: 2}}},
+		{language: "in", entries: []*testResEntry{{key: "s", dataType: AttrTypeString, data: 3}}},
+	}}})
+
+	tests := []struct {
+		locale string
+		value  string
+	}{
+		{"en-GB", "other"},
+		{"en-US", "one"},
+		{"en", "one"},
+		{"de-AT", "few"},
+		{"id", "many"},
+		{"fr", "one"},
+	}
+
+	for _, tt := range tests {
+		e, err := table.getEntryForLocale(0x7f010000, language.MustParse(tt.locale))
+		if err != nil {
+			t.Errorf("%s: %s", tt.locale, err.Error())
+		} else if v := e.value.String(); v != tt.value {
+			t.Errorf("%s: got '%s', expected '%s'", tt.locale, v, tt.value)
+		}
+	}
+}
+
+func TestPluralsResource(t *testing.T) {
+	const (
+		other = 0x01000004
+		one   = 0x01000006
+		few   = 0x01000008
+		many  = 0x01000009
+	)
+
+	// The strings are the names of the quantities, see parseTestArsc
+	a := &APK{resources: parseTestArsc(t, []testResType{{name: "plurals", configs: []testResConfig{
+		{entries: []*testResEntry{{key: "items", isMap: true, complex: []testResMapItem{
+			{one, AttrTypeString, 0}, {other, AttrTypeString, 1},
+		}}}},
+		{language: "ru", entries: []*testResEntry{{key: "items", isMap: true, complex: []testResMapItem{
+			{one, AttrTypeString, 0}, {few, AttrTypeString, 2}, {many, AttrTypeString, 3}, {other, AttrTypeString, 1},
+		}}}},
+		// no "few", falls back to "other"
+		{language: "pl", entries: []*testResEntry{{key: "items", isMap: true, complex: []testResMapItem{
+			{one, AttrTypeString, 0}, {other, AttrTypeString, 1},
+		}}}},
+	}}})}
+
+	tests := []struct {
+		locale   string
+		quantity int
+		form     string
+	}{
+		{"en", 1, "one"},
+		{"en", 0, "other"},
+		{"en", 2, "other"},
+		{"en", -1, "one"},
+		{"ru", 1, "one"},
+		{"ru", 21, "one"},
+		{"ru", 2, "few"},
+		{"ru", 4, "few"},
+		{"ru", 22, "few"},
+		{"ru", 5, "many"},
+		{"ru", 11, "many"},
+		{"ru", 0, "many"},
+		{"pl", 1, "one"},
+		{"pl", 3, "other"},
+	}
+
+	for _, tt := range tests {
+		form, err := a.PluralsResource("items", tt.quantity, language.MustParse(tt.locale))
+		if err != nil {
+			t.Errorf("%s %d: %s", tt.locale, tt.quantity, err.Error())
+		} else if form != tt.form {
+			t.Errorf("%s %d: got '%s', expected '%s'", tt.locale, tt.quantity, form, tt.form)
+		}
+	}
+
+	if _, err := a.PluralsResource("missing", 1, language.English); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMalformedMapEntry(t *testing.T) {
+	a := &APK{resources: parseTestArsc(t, []testResType{{name: "plurals", configs: []testResConfig{
+		{entries: []*testResEntry{{key: "items", isMap: true, truncated: true, complex: []testResMapItem{
+			{0x01000004, AttrTypeString, 1},
+		}}}},
+	}}})}
+
+	// The lookup itself doesn't read the map
+	e, err := a.resources.GetResourceEntry(0x7f010000)
+	if err != nil {
+		t.Fatalf("GetResourceEntry failed: %s", err.Error())
+	} else if !e.IsComplex() || e.Key != "items" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+
+	if _, err := a.PluralsResource("items", 1, language.English); err == nil {
+		t.Error("Expected an error for the truncated map")
+	}
+}
+
+func TestEntryReader(t *testing.T) {
+	le := binary.LittleEndian
+	// offsets of entries 0-2, then the entries
+	chunk := make([]byte, 12+8)
+	le.PutUint32(chunk[0:], 0)
+	le.PutUint32(chunk[4:], 0xffffffff)
+	le.PutUint32(chunk[8:], 2)
+	typ := &resourceType{chunkData: chunk, entryCount: 3, entriesStart: 12, indexesStart: 0}
+
+	if r, err := typ.entryReader(0); err != nil || r == nil {
+		t.Errorf("Entry 0: %v %v", r, err)
+	}
+	if r, err := typ.entryReader(1); err != nil || r != nil {
+		t.Errorf("Missing entry 1: %v %v", r, err)
+	}
+	if _, err := typ.entryReader(2); err == nil {
+		t.Error("Expected an error for the unaligned entry 2")
+	}
+	if r, err := typ.entryReader(3); err != nil || r != nil {
+		t.Errorf("Entry 3 past the end: %v %v", r, err)
+	}
+}
@@ -0,0 +1,95 @@
+package apkparser
+
+import (
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Room database schema exported by the Room annotation processor, see APK.RoomDatabaseSchemas.
+type RoomSchema struct {
+	// Path of the schema file in the APK.
+	File     string
+	Version  int
+	Entities []EntitySchema
+}
+
+// One table of RoomSchema.
+type EntitySchema struct {
+	TableName string
+	Columns   []string
+	Indices   []IndexSchema
+}
+
+// One index of EntitySchema.
+type IndexSchema struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+type roomSchemaJson struct {
+	FormatVersion int `json:"formatVersion"`
+	Database      *struct {
+		Version  int `json:"version"`
+		Entities []struct {
+			TableName string `json:"tableName"`
+			Fields    []struct {
+				ColumnName string `json:"columnName"`
+			} `json:"fields"`
+			Indices []struct {
+				Name        string   `json:"name"`
+				Unique      bool     `json:"unique"`
+				ColumnNames []string `json:"columnNames"`
+			} `json:"indices"`
+		} `json:"entities"`
+	} `json:"database"`
+}
+
+// Returns Room schemas the app bundles in assets, which is usually done for migration tests.
+// They are in <version>.json files, in assets/databases/ or assets/<database class name>/.
+func (a *APK) RoomDatabaseSchemas() ([]RoomSchema, error) {
+	var res []RoomSchema
+	for _, f := range a.zip.FilesOrdered {
+		if f.IsDir || !strings.HasPrefix(f.Name, "assets/") || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+
+		base := path.Base(f.Name)
+		if _, err := strconv.Atoi(strings.TrimSuffix(base, ".json")); err != nil {
+			continue
+		}
+
+		data, err := a.readFile(f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var js roomSchemaJson
+		if err := json.Unmarshal(data, &js); err != nil || js.FormatVersion == 0 || js.Database == nil {
+			continue
+		}
+
+		schema := RoomSchema{
+			File:    f.Name,
+			Version: js.Database.Version,
+		}
+		for _, e := range js.Database.Entities {
+			entity := EntitySchema{TableName: e.TableName}
+			for _, field := range e.Fields {
+				entity.Columns = append(entity.Columns, field.ColumnName)
+			}
+			for _, idx := range e.Indices {
+				entity.Indices = append(entity.Indices, IndexSchema{
+					Name:    idx.Name,
+					Unique:  idx.Unique,
+					Columns: idx.ColumnNames,
+				})
+			}
+			schema.Entities = append(schema.Entities, entity)
+		}
+		res = append(res, schema)
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package apkparser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Multiple errors, returned by ScanDir.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Finds all .apk files in the dir tree, opens them using up to workers goroutines and calls fn
+// for each one. The APK is closed when fn returns, fn must be safe to call concurrently.
+// The APKs are bound to an internal context (see APK.WithContext) already while they are opened,
+// it is cancelled when any fn fails, so even parsing of the manifest and resources.arsc stops then.
+//
+// APKs which fail to open are skipped. When fn returns an error or ctx is cancelled, no more
+// APKs are opened. All errors (including the ones from opening APKs) are returned as MultiError,
+// nil is returned if there were none.
+func ScanDir(ctx context.Context, dir string, workers int, fn func(*APK) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var errsMu sync.Mutex
+	var errs MultiError
+	var fnFailed bool
+	addErr := func(err error, fromFn bool) {
+		errsMu.Lock()
+		errs = append(errs, err)
+		fnFailed = fnFailed || fromFn
+		errsMu.Unlock()
+	}
+
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				apk, err := openAPK(ctx, path)
+				if err != nil {
+					// Cancellation is reported once, by the walk or the failed fn.
+					if ctx.Err() == nil {
+						addErr(fmt.Errorf("%s: %s", path, err.Error()), false)
+					}
+					continue
+				}
+
+				err = fn(apk)
+				apk.Close()
+				if err != nil {
+					addErr(fmt.Errorf("%s: %s", path, err.Error()), true)
+					cancel()
+				}
+			}
+		}()
+	}
+
+	walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			addErr(err, false)
+			return nil
+		}
+
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(info.Name()), ".apk") {
+			return nil
+		}
+
+		select {
+		case paths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(paths)
+	wg.Wait()
+
+	// Cancellation caused by fn is already in errs.
+	if walkErr != nil && !(walkErr == context.Canceled && fnFailed) {
+		errs = append(errs, walkErr)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,96 @@
+package apkparser
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeTestAPKDir(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{"AndroidManifest.xml": testManifest()}
+	writeTestAPK(t, dir, "a.apk", files)
+	writeTestAPK(t, dir, "b.APK", files)
+	writeTestAPK(t, filepath.Join(dir, "sub"), "c.apk", files)
+	writeTestAPK(t, dir, "not-an-apk.zip", files)
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "broken.apk"), []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestScanDir(t *testing.T) {
+	dir := writeTestAPKDir(t)
+
+	var mu sync.Mutex
+	var seen []string
+	err := ScanDir(context.Background(), dir, 2, func(a *APK) error {
+		mu.Lock()
+		seen = append(seen, a.packageName())
+		mu.Unlock()
+		return nil
+	})
+
+	if len(seen) != 3 {
+		t.Errorf("fn called for %v", seen)
+	}
+
+	errs, ok := err.(MultiError)
+	if !ok || len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.apk") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestScanDirCancel(t *testing.T) {
+	dir := writeTestAPKDir(t)
+
+	fnErr := errors.New("fn failed")
+	calls := 0
+	err := ScanDir(context.Background(), dir, 1, func(a *APK) error {
+		calls++
+		return fnErr
+	})
+
+	// The walk and APKs opened after the failure see the cancellation, but it isn't reported.
+	errs, ok := err.(MultiError)
+	if calls != 1 || !ok || len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), ": fn failed") {
+		t.Errorf("%d calls, error %v", calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls = 0
+	err = ScanDir(ctx, dir, 2, func(a *APK) error {
+		calls++
+		return nil
+	})
+
+	errs, ok = err.(MultiError)
+	if calls != 0 || !ok || len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("%d calls, error %v", calls, err)
+	}
+}
+
+func TestOpenAPKContext(t *testing.T) {
+	path := writeTestAPK(t, t.TempDir(), "a.apk", map[string][]byte{"AndroidManifest.xml": testManifest()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if a, err := openAPK(ctx, path); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+		if a != nil {
+			a.Close()
+		}
+	}
+}
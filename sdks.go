@@ -0,0 +1,248 @@
+package apkparser
+
+// Returns the version of Google Play Billing Library used by this app. The version
+// might be "" if the library is present, but its version could not be determined.
+//
+// Returns ErrNotFound if the app doesn't use the library.
+func (a *APK) PlayBillingVersion() (string, error) {
+	if ver, prs := a.metaData("com.google.android.play.billingclient.version"); prs {
+		return ver, nil
+	}
+
+	if ver, prs := a.libraryVersion("com.android.billingclient_billing"); prs {
+		return ver, nil
+	}
+
+	found, err := a.hasDexClass("com.android.billingclient.api.BillingClient")
+	if err != nil {
+		return "", err
+	} else if !found {
+		return "", ErrNotFound
+	}
+	return "", nil
+}
+
+// Known SDK, detected by the class name prefixes in dex files.
+type sdkSignature struct {
+	Name     string
+	Prefixes []string
+	// Library name for the META-INF/*.version file, see APK.libraryVersion. Optional.
+	Library string
+}
+
+// Returns the signatures which match any class referenced from the dex files.
+func (a *APK) detectSdks(sigs []sdkSignature) ([]*sdkSignature, error) {
+	var res []*sdkSignature
+	for i := range sigs {
+		for _, prefix := range sigs[i].Prefixes {
+			found, err := a.hasDexClass(prefix)
+			if err != nil {
+				return nil, err
+			} else if found {
+				res = append(res, &sigs[i])
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// Same as detectSdks, but returns only the names.
+func (a *APK) detectSdkNames(sigs []sdkSignature) ([]string, error) {
+	found, err := a.detectSdks(sigs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(found))
+	for _, sig := range found {
+		res = append(res, sig.Name)
+	}
+	return res, nil
+}
+
+// Advertising SDK used by the app.
+type AdsSDK struct {
+	Name string
+	// Empty if the version couldn't be determined.
+	Version string
+}
+
+var adsSdks = []sdkSignature{
+	{Name: "AdMob", Prefixes: []string{"com.google.android.gms.ads"}, Library: "com.google.android.gms_play-services-ads"},
+	{Name: "Facebook Audience Network", Prefixes: []string{"com.facebook.ads"}},
+	{Name: "IronSource", Prefixes: []string{"com.ironsource.mediationsdk"}},
+	{Name: "AppLovin", Prefixes: []string{"com.applovin"}},
+	{Name: "Unity Ads", Prefixes: []string{"com.unity3d.ads", "com.unity3d.services.ads"}},
+	{Name: "Vungle", Prefixes: []string{"com.vungle.warren", "com.vungle.ads"}},
+	{Name: "Chartboost", Prefixes: []string{"com.chartboost.sdk"}},
+	{Name: "InMobi", Prefixes: []string{"com.inmobi.ads", "com.inmobi.sdk"}},
+	{Name: "AdColony", Prefixes: []string{"com.adcolony.sdk"}},
+	{Name: "Mintegral", Prefixes: []string{"com.mbridge.msdk"}},
+	{Name: "Pangle", Prefixes: []string{"com.bytedance.sdk.openadsdk"}},
+	{Name: "Yandex Mobile Ads", Prefixes: []string{"com.yandex.mobile.ads"}},
+}
+
+// Returns the advertising SDKs the app contains, detected from the class names in its dex files.
+func (a *APK) AdsSDKs() ([]AdsSDK, error) {
+	found, err := a.detectSdks(adsSdks)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]AdsSDK, 0, len(found))
+	for _, sig := range found {
+		sdk := AdsSDK{Name: sig.Name}
+		if sig.Library != "" {
+			sdk.Version, _ = a.libraryVersion(sig.Library)
+		}
+		res = append(res, sdk)
+	}
+	return res, nil
+}
+
+var crashReportingSdks = []sdkSignature{
+	{Name: "Firebase Crashlytics", Prefixes: []string{"com.google.firebase.crashlytics", "com.crashlytics.android"}},
+	{Name: "Bugsnag", Prefixes: []string{"com.bugsnag.android"}},
+	{Name: "Sentry", Prefixes: []string{"io.sentry.android", "io.sentry"}},
+	{Name: "Rollbar", Prefixes: []string{"com.rollbar.android"}},
+	{Name: "ACRA", Prefixes: []string{"org.acra"}},
+	{Name: "Instabug", Prefixes: []string{"com.instabug"}},
+}
+
+// Returns names of the crash reporting SDKs the app contains.
+func (a *APK) CrashReportingSDKs() ([]string, error) {
+	return a.detectSdkNames(crashReportingSdks)
+}
+
+var pushNotificationSdks = []sdkSignature{
+	{Name: "Firebase Cloud Messaging", Prefixes: []string{"com.google.firebase.messaging.FirebaseMessagingService"}},
+	{Name: "OneSignal", Prefixes: []string{"com.onesignal"}},
+	{Name: "Braze", Prefixes: []string{"com.braze.push", "com.appboy.push"}},
+	{Name: "Airship", Prefixes: []string{"com.urbanairship"}},
+	{Name: "Pushwoosh", Prefixes: []string{"com.pushwoosh"}},
+	{Name: "Huawei Push Kit", Prefixes: []string{"com.huawei.hms.push"}},
+}
+
+// Returns names of the push notification SDKs the app contains.
+func (a *APK) PushNotificationSDKs() ([]string, error) {
+	return a.detectSdkNames(pushNotificationSdks)
+}
+
+var analyticsSdks = []sdkSignature{
+	{Name: "Google Analytics", Prefixes: []string{"com.google.android.gms.analytics"}},
+	{Name: "Firebase Analytics", Prefixes: []string{"com.google.firebase.analytics"}},
+	{Name: "Amplitude", Prefixes: []string{"com.amplitude.api", "com.amplitude.android"}},
+	{Name: "Mixpanel", Prefixes: []string{"com.mixpanel.android"}},
+	{Name: "Segment", Prefixes: []string{"com.segment.analytics"}},
+	{Name: "Heap", Prefixes: []string{"io.heap.android", "com.heapanalytics.android"}},
+	{Name: "Flurry", Prefixes: []string{"com.flurry.android"}},
+	{Name: "AppsFlyer", Prefixes: []string{"com.appsflyer"}},
+	{Name: "Adjust", Prefixes: []string{"com.adjust.sdk"}},
+}
+
+// Returns names of the analytics SDKs the app contains.
+func (a *APK) AnalyticsSDKs() ([]string, error) {
+	return a.detectSdkNames(analyticsSdks)
+}
+
+var databaseSdks = []sdkSignature{
+	{Name: "SQLite", Prefixes: []string{"android.database.sqlite"}},
+	{Name: "Room", Prefixes: []string{"androidx.room", "android.arch.persistence.room"}},
+	{Name: "Realm", Prefixes: []string{"io.realm"}},
+	{Name: "ObjectBox", Prefixes: []string{"io.objectbox"}},
+	{Name: "GreenDAO", Prefixes: []string{"org.greenrobot.greendao"}},
+	{Name: "SQLCipher", Prefixes: []string{"net.sqlcipher", "net.zetetic.database.sqlcipher"}},
+	{Name: "Couchbase Lite", Prefixes: []string{"com.couchbase.lite"}},
+}
+
+// Returns names of the databases the app uses. SQLite is the framework's android.database.sqlite,
+// so it is reported whenever the app references it, even if it uses it through another library.
+func (a *APK) DatabaseSDKs() ([]string, error) {
+	return a.detectSdkNames(databaseSdks)
+}
+
+var authenticationSdks = []sdkSignature{
+	{Name: "Firebase Authentication", Prefixes: []string{"com.google.firebase.auth"}},
+	{Name: "Google Sign-In", Prefixes: []string{"com.google.android.gms.auth.api.signin"}},
+	{Name: "Auth0", Prefixes: []string{"com.auth0.android"}},
+	{Name: "Okta", Prefixes: []string{"com.okta.oidc", "com.okta.authfoundation"}},
+	{Name: "AppAuth", Prefixes: []string{"net.openid.appauth"}},
+	{Name: "Facebook Login", Prefixes: []string{"com.facebook.login"}},
+	{Name: "MSAL", Prefixes: []string{"com.microsoft.identity.client"}},
+}
+
+// Returns names of the authentication SDKs the app contains.
+func (a *APK) AuthenticationSDKs() ([]string, error) {
+	return a.detectSdkNames(authenticationSdks)
+}
+
+var mapSdks = []sdkSignature{
+	{Name: "Google Maps", Prefixes: []string{"com.google.android.gms.maps"}},
+	{Name: "Mapbox", Prefixes: []string{"com.mapbox.maps", "com.mapbox.mapboxsdk"}},
+	{Name: "HERE", Prefixes: []string{"com.here.sdk", "com.here.android.mpa"}},
+	{Name: "OSMDroid", Prefixes: []string{"org.osmdroid"}},
+	{Name: "Yandex MapKit", Prefixes: []string{"com.yandex.mapkit"}},
+	{Name: "Huawei Map Kit", Prefixes: []string{"com.huawei.hms.maps"}},
+}
+
+// Returns names of the mapping SDKs the app contains.
+func (a *APK) MapSDKs() ([]string, error) {
+	return a.detectSdkNames(mapSdks)
+}
+
+var mediaSdks = []sdkSignature{
+	{Name: "ExoPlayer", Prefixes: []string{"com.google.android.exoplayer2", "androidx.media3.exoplayer"}},
+	{Name: "Brightcove", Prefixes: []string{"com.brightcove.player"}},
+	{Name: "Kaltura", Prefixes: []string{"com.kaltura.playkit"}},
+	{Name: "Wowza", Prefixes: []string{"com.wowza.gocoder"}},
+	{Name: "JW Player", Prefixes: []string{"com.jwplayer"}},
+	{Name: "VLC", Prefixes: []string{"org.videolan.libvlc"}},
+}
+
+// Returns names of the media playback SDKs the app contains.
+func (a *APK) MediaSDKs() ([]string, error) {
+	return a.detectSdkNames(mediaSdks)
+}
+
+var securitySdks = []sdkSignature{
+	{Name: "TrustKit", Prefixes: []string{"com.datatheorem.android.trustkit"}},
+	{Name: "OkHttp CertificatePinner", Prefixes: []string{"okhttp3.CertificatePinner", "com.squareup.okhttp.CertificatePinner"}},
+	{Name: "AndroidX Security", Prefixes: []string{"androidx.security.crypto"}},
+	{Name: "Conscrypt", Prefixes: []string{"org.conscrypt"}},
+	{Name: "Bouncy Castle", Prefixes: []string{"org.bouncycastle", "org.spongycastle"}},
+}
+
+// Returns names of the security and certificate pinning SDKs the app contains.
+func (a *APK) SecuritySDKs() ([]string, error) {
+	return a.detectSdkNames(securitySdks)
+}
+
+var rootDetectionSdks = []sdkSignature{
+	{Name: "SafetyNet", Prefixes: []string{"com.google.android.gms.safetynet"}},
+	{Name: "Play Integrity", Prefixes: []string{"com.google.android.play.core.integrity"}},
+	{Name: "RootBeer", Prefixes: []string{"com.scottyab.rootbeer"}},
+	{Name: "Promon SHIELD", Prefixes: []string{"no.promon.shield"}},
+	{Name: "DexGuard RootDetector", Prefixes: []string{"com.guardsquare.dexguard.rasp", "dexguard.util.RootDetector"}},
+}
+
+// Returns names of the root detection and device attestation SDKs the app contains.
+func (a *APK) RootDetectionSDKs() ([]string, error) {
+	return a.detectSdkNames(rootDetectionSdks)
+}
+
+var virtualizationSdks = []sdkSignature{
+	{Name: "VirtualApp", Prefixes: []string{"com.lody.virtual"}},
+	{Name: "DroidPlugin", Prefixes: []string{"com.morgoo.droidplugin"}},
+	{Name: "RePlugin", Prefixes: []string{"com.qihoo360.replugin"}},
+	{Name: "Robust", Prefixes: []string{"com.meituan.robust"}},
+	{Name: "VirtualAPK", Prefixes: []string{"com.didi.virtualapk"}},
+	{Name: "DualSpace", Prefixes: []string{"com.polestar.multiaccount", "com.polestar.clone"}},
+}
+
+// Returns true if the app contains a virtualization or plugin framework which runs
+// other apps (or unreviewed code) inside it.
+func (a *APK) VirtualizationDetected() (bool, error) {
+	found, err := a.detectSdks(virtualizationSdks)
+	return len(found) != 0, err
+}
@@ -0,0 +1,379 @@
+package apkparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var awsRegionRegexp = regexp.MustCompile(`\b(us-gov|us|eu|ap|sa|ca|me|af|il|mx|cn)-(north|south|east|west|central|northeast|northwest|southeast|southwest)-\d\b`)
+
+// Returns true if the method calls any method of class in the package (Lcom/example/ descriptor prefix)
+// and isn't itself in that package.
+func (d *dexFile) callsIntoPackage(code *dexMethodCode, descPrefix string) bool {
+	if strings.HasPrefix(d.types[code.Class], descPrefix) {
+		return false
+	}
+
+	for _, idx := range code.Invokes {
+		if strings.HasPrefix(d.types[d.methods[idx].Class], descPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns files from assets/ and res/raw/ with one of the extensions.
+func (a *APK) assetFiles(extensions ...string) []string {
+	var res []string
+	for _, f := range a.zip.FilesOrdered {
+		if f.IsDir || !(strings.HasPrefix(f.Name, "assets/") || strings.HasPrefix(f.Name, "res/raw/")) {
+			continue
+		}
+
+		for _, ext := range extensions {
+			if strings.HasSuffix(f.Name, ext) {
+				res = append(res, f.Name)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// Returns the AWS region hardcoded in the app, e.g. us-east-1. Looks at string constants in app's
+// methods which call into the AWS SDK (com.amazonaws, com.amplifyframework) and JSON configs
+// (awsconfiguration.json, amplifyconfiguration.json...) in assets/ and res/raw/.
+//
+// Returns ErrNotFound if no region is found.
+func (a *APK) AWSRegion() (string, error) {
+	var res string
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if res != "" || !(d.callsIntoPackage(code, "Lcom/amazonaws/") || d.callsIntoPackage(code, "Lcom/amplifyframework/")) {
+			return
+		}
+
+		for _, idx := range code.Strings {
+			if str := d.strings[idx]; str != "" && awsRegionRegexp.FindString(str) == str {
+				res = str
+				return
+			}
+		}
+	})
+	if err != nil {
+		return "", err
+	} else if res != "" {
+		return res, nil
+	}
+
+	for _, name := range a.assetFiles(".json") {
+		data, err := a.readFile(name)
+		if err != nil {
+			return "", err
+		}
+
+		if region := awsRegionRegexp.Find(data); region != nil {
+			return string(region), nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+var (
+	awsAccessKeyIdRegexp = regexp.MustCompile(`^(AKIA|ASIA)[A-Z0-9]{16}$`)
+	awsSecretKeyRegexp   = regexp.MustCompile(`^[A-Za-z0-9/+]{40}$`)
+	hexRegexp            = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// Hardcoded AWS credentials, see APK.AWSCredentials.
+type AWSCredential struct {
+	AccessKeyID string
+	// Secret access key used in the same class as AccessKeyID, empty if none was found.
+	SecretKey string
+	// Class name which uses the access key ID, empty if it is only in the string pool.
+	FoundInClass string
+}
+
+// Returns AWS access key IDs (AKIA..., ASIA...) hardcoded in dex files, paired with secret keys
+// (40 chars of base64) from the same class. Secret keys are only looked for in classes with an
+// access key ID, otherwise there would be too many false positives.
+func (a *APK) AWSCredentials() ([]AWSCredential, error) {
+	type classStrings struct {
+		keyIds, secrets []string
+	}
+
+	var classes []string
+	found := make(map[string]*classStrings)
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		cls := descriptorToClassName(d.types[code.Class])
+		for _, idx := range code.Strings {
+			str := d.strings[idx]
+			isKeyId := awsAccessKeyIdRegexp.MatchString(str)
+			if !isKeyId && (!awsSecretKeyRegexp.MatchString(str) || hexRegexp.MatchString(str)) {
+				continue
+			}
+
+			cs := found[cls]
+			if cs == nil {
+				cs = &classStrings{}
+				found[cls] = cs
+				classes = append(classes, cls)
+			}
+
+			if isKeyId {
+				cs.keyIds = append(cs.keyIds, str)
+			} else {
+				cs.secrets = append(cs.secrets, str)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := []AWSCredential{}
+	seen := make(map[string]bool)
+	for _, cls := range classes {
+		cs := found[cls]
+		for i, keyId := range cs.keyIds {
+			cred := AWSCredential{
+				AccessKeyID:  keyId,
+				FoundInClass: cls,
+			}
+			if i < len(cs.secrets) {
+				cred.SecretKey = cs.secrets[i]
+			}
+			seen[keyId] = true
+			res = append(res, cred)
+		}
+	}
+
+	err = a.walkDexStrings(func(d *dexFile, str string) {
+		if !seen[str] && awsAccessKeyIdRegexp.MatchString(str) {
+			seen[str] = true
+			res = append(res, AWSCredential{AccessKeyID: str})
+		}
+	})
+	return res, err
+}
+
+var googleApiKeyRegexp = regexp.MustCompile(`AIza[A-Za-z0-9_-]{35}`)
+
+// Returns the first Google API key (AIza...) found in google-services.json in assets,
+// the google_api_key string resource generated by the Google Services plugin or dex string pools.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) GoogleAPIKey() (string, error) {
+	if gs := a.googleServicesJson(); gs != nil {
+		for _, client := range gs.Client {
+			for _, key := range client.ApiKey {
+				if googleApiKeyRegexp.MatchString(key.CurrentKey) {
+					return key.CurrentKey, nil
+				}
+			}
+		}
+	}
+
+	if key, err := a.stringResource("google_api_key"); err == nil && googleApiKeyRegexp.MatchString(key) {
+		return key, nil
+	} else if err != nil && err != ErrNotFound {
+		return "", err
+	}
+
+	var res string
+	err := a.walkDexStrings(func(d *dexFile, str string) {
+		if res == "" {
+			res = googleApiKeyRegexp.FindString(str)
+		}
+	})
+	if err != nil {
+		return "", err
+	} else if res == "" {
+		return "", ErrNotFound
+	}
+	return res, nil
+}
+
+var stripePublishableKeyRegexp = regexp.MustCompile(`pk_(live|test)_[A-Za-z0-9]{24,}`)
+
+// Returns the Stripe publishable key found in dex string pools. Live keys (pk_live_...) are
+// preferred over test keys (pk_test_...), check the prefix to tell which one was returned.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) StripePublishableKey() (string, error) {
+	var live, test string
+	err := a.walkDexStrings(func(d *dexFile, str string) {
+		if live != "" {
+			return
+		}
+
+		for _, m := range stripePublishableKeyRegexp.FindAllStringSubmatch(str, -1) {
+			if m[1] == "live" {
+				live = m[0]
+				return
+			} else if test == "" {
+				test = m[0]
+			}
+		}
+	})
+	if err != nil {
+		return "", err
+	} else if live != "" {
+		return live, nil
+	} else if test != "" {
+		return test, nil
+	}
+	return "", ErrNotFound
+}
+
+var (
+	twitterConsumerKeyRegexp    = regexp.MustCompile(`^[A-Za-z0-9]{25}$`)
+	twitterConsumerSecretRegexp = regexp.MustCompile(`^[A-Za-z0-9]{50}$`)
+)
+
+// Returns the Twitter Kit consumer key, from com.twitter.sdk.android.CONSUMER_KEY meta-data
+// or a 25 characters long string constant in app's methods which call into the Twitter SDK.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) TwitterConsumerKey() (string, error) {
+	return a.twitterCredential("com.twitter.sdk.android.CONSUMER_KEY", twitterConsumerKeyRegexp)
+}
+
+// Returns the Twitter Kit consumer secret, from com.twitter.sdk.android.CONSUMER_SECRET meta-data
+// or a 50 characters long string constant in app's methods which call into the Twitter SDK.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) TwitterConsumerSecret() (string, error) {
+	return a.twitterCredential("com.twitter.sdk.android.CONSUMER_SECRET", twitterConsumerSecretRegexp)
+}
+
+func (a *APK) twitterCredential(metaDataName string, re *regexp.Regexp) (string, error) {
+	if val, prs := a.metaData(metaDataName); prs && val != "" {
+		return val, nil
+	}
+	return a.sdkStringConstant("Lcom/twitter/sdk/", re)
+}
+
+// Returns the first string constant matching re from app's methods which call into
+// the SDK package (descriptor prefix like Lcom/example/sdk/), or ErrNotFound.
+func (a *APK) sdkStringConstant(descPrefix string, re *regexp.Regexp) (string, error) {
+	var res string
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if res != "" || !d.callsIntoPackage(code, descPrefix) {
+			return
+		}
+
+		for _, idx := range code.Strings {
+			if str := d.strings[idx]; re.MatchString(str) {
+				res = str
+				return
+			}
+		}
+	})
+	if err != nil {
+		return "", err
+	} else if res == "" {
+		return "", ErrNotFound
+	}
+	return res, nil
+}
+
+var facebookAppIdRegexp = regexp.MustCompile(`^[0-9]{10,20}$`)
+
+// Returns the Facebook App ID (a number, usually 15 or 16 digits long) from
+// com.facebook.sdk.ApplicationId meta-data or the facebook_app_id string resource.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) FacebookAppID() (string, error) {
+	if id, prs := a.metaData("com.facebook.sdk.ApplicationId"); prs {
+		// The SDK accepts "fb" prefix too, because numbers in meta-data would be parsed as int.
+		if strings.HasPrefix(strings.ToLower(id), "fb") {
+			id = id[2:]
+		}
+		if facebookAppIdRegexp.MatchString(id) {
+			return id, nil
+		}
+	}
+
+	id, err := a.stringResource("facebook_app_id")
+	if err != nil {
+		return "", err
+	} else if !facebookAppIdRegexp.MatchString(id) {
+		return "", ErrNotFound
+	}
+	return id, nil
+}
+
+var branchKeyRegexp = regexp.MustCompile(`^key_(live|test)_[A-Za-z0-9]{20,}$`)
+
+// Returns the Branch.io SDK key, from io.branch.sdk.BranchKey (or io.branch.sdk.BranchKey.test)
+// meta-data, or a key_live_.../key_test_... string constant in the dex files, which is
+// where it ends up when the app passes it to the SDK in code.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) BranchIOKey() (string, error) {
+	for _, name := range []string{"io.branch.sdk.BranchKey", "io.branch.sdk.BranchKey.test"} {
+		if key, prs := a.metaData(name); prs && key != "" {
+			return key, nil
+		}
+	}
+
+	var res string
+	err := a.walkDexStrings(func(d *dexFile, str string) {
+		if res == "" && branchKeyRegexp.MatchString(str) {
+			res = str
+		}
+	})
+	if err != nil {
+		return "", err
+	} else if res == "" {
+		return "", ErrNotFound
+	}
+	return res, nil
+}
+
+var mixpanelTokenRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Returns the Mixpanel project token, a 32 characters long hex string constant in app's methods
+// which call into the Mixpanel SDK (usually MixpanelAPI.getInstance).
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) MixpanelToken() (string, error) {
+	return a.sdkStringConstant("Lcom/mixpanel/android/", mixpanelTokenRegexp)
+}
+
+var sentryDsnRegexp = regexp.MustCompile(`https://[a-f0-9]+(:[a-f0-9]+)?@[a-z0-9.-]+(:[0-9]+)?/\d+`)
+
+// Returns the Sentry DSN from io.sentry.dsn meta-data or dex string pools.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) SentryDSN() (string, error) {
+	if dsn, prs := a.metaData("io.sentry.dsn"); prs && dsn != "" {
+		return dsn, nil
+	}
+
+	var res string
+	err := a.walkDexStrings(func(d *dexFile, str string) {
+		if res == "" {
+			res = sentryDsnRegexp.FindString(str)
+		}
+	})
+	if err != nil {
+		return "", err
+	} else if res == "" {
+		return "", ErrNotFound
+	}
+	return res, nil
+}
+
+var applovinSdkKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{86}$`)
+
+// Returns the AppLovin SDK key, from applovin.sdk.key meta-data or an 86 characters long
+// string constant in app's methods which call into the AppLovin SDK.
+//
+// Returns ErrNotFound if there is none.
+func (a *APK) ApplovinSDKKey() (string, error) {
+	if key, prs := a.metaData("applovin.sdk.key"); prs && key != "" {
+		return key, nil
+	}
+	return a.sdkStringConstant("Lcom/applovin/", applovinSdkKeyRegexp)
+}
@@ -0,0 +1,300 @@
+package apkparser
+
+import (
+	"bytes"
+	"crypto/x509"
+	"debug/elf"
+	"fmt"
+	"strings"
+)
+
+// Returns true and the reason if the APK looks like a repackaged (possibly trojanized) app:
+// trustedCerts has a certificate for the package name and the APK is signed with a different one.
+// trustedCerts may be nil. The signature itself is not verified.
+//
+// If the certificate check doesn't fire, returns false, but possibly with a low confidence reason,
+// when none of the dex files defines classes from the manifest package's top-level domain
+// (e.g. com.example for com.example.app). Apps usually keep at least some of their code there
+// and repackagers often don't bother, but apps built from a template or renamed after
+// the release look the same.
+func (a *APK) RepackagedApp(trustedCerts map[string]*x509.Certificate) (bool, string, error) {
+	pkg := a.packageName()
+
+	if trusted := trustedCerts[pkg]; trusted != nil {
+		cert, err := a.signingCertificate()
+		if err != nil {
+			return false, "", fmt.Errorf("Failed to get the signing certificate: %s", err.Error())
+		}
+
+		if !cert.Equal(trusted) {
+			return true, fmt.Sprintf("signed by '%s' instead of the trusted certificate for %s", cert.Subject, pkg), nil
+		}
+	}
+
+	files, err := a.dexFiles()
+	if err != nil {
+		return false, "", err
+	}
+
+	prefix := pkg
+	if parts := strings.SplitN(pkg, ".", 3); len(parts) == 3 {
+		prefix = parts[0] + "." + parts[1]
+	}
+	prefix = classNameToDescriptor(prefix) + "/"
+
+	classCnt := 0
+	for _, d := range files {
+		for _, cls := range d.classes {
+			if strings.HasPrefix(d.types[cls.Type], prefix) {
+				return false, "", nil
+			}
+		}
+		classCnt += len(d.classes)
+	}
+
+	if classCnt == 0 {
+		return false, "", nil
+	}
+	return false, fmt.Sprintf("low confidence: no dex file defines classes of package %s", pkg), nil
+}
+
+// Returns true if the APK is a Magisk module or the Magisk app itself, detected by
+// assets/module.prop or Magisk's classes in the dex files.
+func (a *APK) MagiskModule() (bool, error) {
+	if a.zip.File["assets/module.prop"] != nil {
+		return true, nil
+	}
+	return a.hasDexClass("com.topjohnwu.magisk")
+}
+
+// Returns true if the APK is an Xposed Framework module, which is declared by
+// <meta-data android:name="xposedmodule" android:value="true"/> or assets/xposed_init.
+func (a *APK) XposedModule() (bool, error) {
+	if val, prs := a.metaData("xposedmodule"); prs && val == "true" {
+		return true, nil
+	}
+	return a.zip.File["assets/xposed_init"] != nil, nil
+}
+
+var dynamicCodeClasses = []string{
+	"dalvik.system.DexClassLoader",
+	"dalvik.system.InMemoryDexClassLoader",
+	"dalvik.system.DelegateLastClassLoader",
+	"dalvik.system.DexFile",
+	"java.lang.reflect.Proxy",
+}
+
+// Returns true if the app's code references classes used to load or generate code at runtime,
+// like DexClassLoader or InMemoryDexClassLoader. The dex files only contain the app's own code,
+// so framework's use of these classes doesn't count.
+//
+// PathClassLoader is not included, it is used way too often for legitimate purposes
+// and the loaded path can't be checked without running the code.
+func (a *APK) UsesDynamicCode() (bool, error) {
+	for _, cls := range dynamicCodeClasses {
+		if found, err := a.hasDexClass(cls); err != nil || found {
+			return found, err
+		}
+	}
+	return false, nil
+}
+
+// Returns true and the detected mechanisms if the app implements certificate pinning. Detects
+// OkHttp's CertificatePinner used from app code, custom X509TrustManagers with non-empty
+// checkServerTrusted, <pin-set> in the network security config and TrustKit.
+func (a *APK) SSLPinningActive() (bool, []string, error) {
+	var res []string
+
+	nsc, err := a.networkSecurityConfig()
+	if err != nil {
+		return false, nil, err
+	} else if nsc != nil && len(nsc.findAll("pin-set")) != 0 {
+		res = append(res, "Network Security Config pin-set")
+	}
+
+	if found, err := a.hasDexClass("com.datatheorem.android.trustkit"); err != nil {
+		return false, nil, err
+	} else if found {
+		res = append(res, "TrustKit")
+	}
+
+	files, err := a.dexFiles()
+	if err != nil {
+		return false, nil, err
+	}
+
+	trustManagers := make(map[string]bool)
+	for _, d := range files {
+		for i := range d.classes {
+			for _, iface := range d.classInterfaces(&d.classes[i]) {
+				if iface == "Ljavax/net/ssl/X509TrustManager;" {
+					trustManagers[d.types[d.classes[i].Type]] = true
+				}
+			}
+		}
+	}
+
+	var okhttp, trustManager bool
+	err = a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if !okhttp && !strings.HasPrefix(d.types[code.Class], "Lokhttp3/") &&
+			(d.invokes(code, "Lokhttp3/CertificatePinner$Builder;", "add") ||
+				d.invokes(code, "Lcom/squareup/okhttp/CertificatePinner$Builder;", "add")) {
+			okhttp = true
+		}
+
+		// Size 1 is just return-void, which trusts everything.
+		if !trustManager && trustManagers[d.types[code.Class]] && code.Size > 1 &&
+			d.strings[d.methods[code.Method].Name] == "checkServerTrusted" {
+			trustManager = true
+		}
+	})
+	if err != nil {
+		return false, nil, err
+	}
+
+	if okhttp {
+		res = append(res, "OkHttp CertificatePinner")
+	}
+	if trustManager {
+		res = append(res, "Custom X509TrustManager")
+	}
+	return len(res) != 0, res, nil
+}
+
+// Returns the anti-debugging techniques the app likely uses: calls to Debug.isDebuggerConnected,
+// TracerPid checks in /proc/self/status, the ro.debuggable property and native libraries importing ptrace.
+func (a *APK) AntiDebugTechniques() ([]string, error) {
+	var debugApi bool
+	err := a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if !debugApi && (d.invokes(code, "Landroid/os/Debug;", "isDebuggerConnected") ||
+			d.invokes(code, "Landroid/os/Debug;", "waitingForDebugger")) {
+			debugApi = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tracerPid, procStatus, roDebuggable bool
+	err = a.walkDexStrings(func(d *dexFile, str string) {
+		switch {
+		case strings.Contains(str, "TracerPid"):
+			tracerPid = true
+		case strings.Contains(str, "/proc/self/status"):
+			procStatus = true
+		case str == "ro.debuggable":
+			roDebuggable = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	if debugApi {
+		res = append(res, "Debug.isDebuggerConnected")
+	}
+	if tracerPid {
+		res = append(res, "TracerPid check")
+	}
+	if procStatus {
+		res = append(res, "/proc/self/status read")
+	}
+	if roDebuggable {
+		res = append(res, "ro.debuggable check")
+	}
+
+	for _, f := range a.zip.FilesOrdered {
+		if !strings.HasPrefix(f.Name, "lib/") || !strings.HasSuffix(f.Name, ".so") {
+			continue
+		}
+
+		data, err := a.readFile(f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if elfImportsSymbol(data, "ptrace") {
+			res = append(res, "native ptrace")
+			break
+		}
+	}
+	return res, nil
+}
+
+func elfImportsSymbol(data []byte, name string) bool {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	syms, err := f.ImportedSymbols()
+	if err != nil {
+		return false
+	}
+
+	for _, s := range syms {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// In order from the oldest.
+var tlsVersions = []string{"SSLv3", "TLSv1", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+
+// Returns the lowest and highest TLS version the app configures: <ssl-version-min> and <ssl-version-max>
+// in the network security config, and protocol names like "TLSv1.2" in methods calling SSLContext.getInstance
+// or SSLSocket.setEnabledProtocols. Either is "" if no version was found.
+func (a *APK) TLSVersionRange() (min, max string, err error) {
+	minIdx, maxIdx := len(tlsVersions), -1
+	add := func(version string) {
+		for i, v := range tlsVersions {
+			if v != version {
+				continue
+			}
+			if i < minIdx {
+				minIdx = i
+			}
+			if i > maxIdx {
+				maxIdx = i
+			}
+		}
+	}
+
+	nsc, err := a.networkSecurityConfig()
+	if err != nil {
+		return "", "", err
+	} else if nsc != nil {
+		for _, name := range []string{"ssl-version-min", "ssl-version-max"} {
+			for _, el := range nsc.findAll(name) {
+				if val, prs := el.attr("value"); prs {
+					add(strings.TrimSpace(val))
+				} else {
+					add(strings.TrimSpace(el.Text))
+				}
+			}
+		}
+	}
+
+	err = a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		if !d.invokes(code, "Ljavax/net/ssl/SSLContext;", "getInstance") &&
+			!d.invokes(code, "Ljavax/net/ssl/SSLSocket;", "setEnabledProtocols") {
+			return
+		}
+
+		for _, idx := range code.Strings {
+			add(d.strings[idx])
+		}
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if maxIdx == -1 {
+		return "", "", nil
+	}
+	return tlsVersions[minIdx], tlsVersions[maxIdx], nil
+}
@@ -0,0 +1,50 @@
+package apkparser
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRepackagedApp(t *testing.T) {
+	dex := func(name string, types ...string) *dexFile {
+		var classes []testDexClass
+		for _, typ := range types {
+			classes = append(classes, testDexClass{typ: typ, super: "Ljava/lang/Object;"})
+		}
+
+		d, err := parseDex(name, buildDex(append([]string{"Ljava/lang/Object;"}, types...),
+			append([]string{"Ljava/lang/Object;"}, types...), nil, classes))
+		if err != nil {
+			t.Fatalf("Failed to parse dex: %s", err.Error())
+		}
+		return d
+	}
+
+	tests := []struct {
+		name   string
+		dex    []*dexFile
+		reason string
+	}{
+		{"no dex", nil, ""},
+		{"own classes", []*dexFile{dex("classes.dex", "Lcom/example/app/Main;")}, ""},
+		{"own domain", []*dexFile{dex("classes.dex", "Lcom/example/lib/Util;")}, ""},
+		{"own classes in secondary dex", []*dexFile{dex("classes.dex", "Landroidx/core/App;"), dex("classes2.dex", "Lcom/example/app/Main;")}, ""},
+		{"foreign classes", []*dexFile{dex("classes.dex", "Landroidx/core/App;"), dex("classes2.dex", "Lcom/other/Main;")}, "low confidence"},
+	}
+
+	for _, test := range tests {
+		a := &APK{
+			dex:      test.dex,
+			manifest: &xmlElement{Name: "manifest", Attrs: []xml.Attr{{Name: xml.Name{Local: "package"}, Value: "com.example.app"}}},
+		}
+		if test.dex == nil {
+			a.dex = []*dexFile{}
+		}
+
+		repackaged, reason, err := a.RepackagedApp(nil)
+		if err != nil || repackaged || !strings.HasPrefix(reason, test.reason) || (test.reason == "") != (reason == "") {
+			t.Errorf("%s: got %v '%s' %v", test.name, repackaged, reason, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package apkparser
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	apkSigBlockMagic = "APK Sig Block 42"
+
+	apkSigSchemeV2 = 0x7109871a
+	apkSigSchemeV3 = 0xf05368c0
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	Crls             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// Returns the certificate of the first signer. It is taken from the v3 or v2 APK Signing Block,
+// or from the v1 META-INF/*.RSA (DSA, EC) signature, in that order.
+//
+// The signature is NOT verified, use github.com/avast/apkverifier for that.
+func (a *APK) signingCertificate() (*x509.Certificate, error) {
+	certs, err := a.signingBlockCertificates()
+	if err != nil || len(certs) == 0 {
+		certs, err = a.v1Certificates()
+	}
+
+	if err != nil {
+		return nil, err
+	} else if len(certs) == 0 {
+		return nil, ErrNotFound
+	}
+	return certs[0], nil
+}
+
+func (a *APK) v1Certificates() ([]*x509.Certificate, error) {
+	for _, f := range a.zip.FilesOrdered {
+		dir, name := "", f.Name
+		if idx := strings.LastIndexByte(name, '/'); idx != -1 {
+			dir, name = name[:idx], name[idx+1:]
+		}
+
+		if dir != "META-INF" || !(strings.HasSuffix(name, ".RSA") || strings.HasSuffix(name, ".DSA") || strings.HasSuffix(name, ".EC")) {
+			continue
+		}
+
+		data, err := a.readFile(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		return parsePkcs7Certificates(data)
+	}
+	return nil, nil
+}
+
+func parsePkcs7Certificates(data []byte) ([]*x509.Certificate, error) {
+	var info pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("Failed to parse PKCS7 content info: %s", err.Error())
+	}
+
+	var signed pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signed); err != nil {
+		return nil, fmt.Errorf("Failed to parse PKCS7 signed data: %s", err.Error())
+	}
+	return x509.ParseCertificates(signed.Certificates.Bytes)
+}
+
+// Returns certificates from the first signer of v3 or v2 scheme, nil if the APK has no APK Signing Block.
+func (a *APK) signingBlockCertificates() ([]*x509.Certificate, error) {
+	block, err := a.readSigningBlock()
+	if err != nil || block == nil {
+		return nil, err
+	}
+
+	pairs := make(map[uint32][]byte)
+	for len(block) >= 12 {
+		size := binary.LittleEndian.Uint64(block)
+		if size < 4 || size > uint64(len(block)-8) {
+			return nil, fmt.Errorf("Invalid APK Signing Block pair size: %d", size)
+		}
+		id := binary.LittleEndian.Uint32(block[8:])
+		pairs[id] = block[12 : 8+size]
+		block = block[8+size:]
+	}
+
+	for _, id := range []uint32{apkSigSchemeV3, apkSigSchemeV2} {
+		value, prs := pairs[id]
+		if !prs {
+			continue
+		}
+
+		// signers -> first signer -> signed data -> (digests, certificates)
+		signers, _, err := readLengthPrefixed(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid signers in scheme 0x%08x: %s", id, err.Error())
+		}
+
+		signer, _, err := readLengthPrefixed(signers)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid signer in scheme 0x%08x: %s", id, err.Error())
+		}
+
+		signedData, _, err := readLengthPrefixed(signer)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid signed data in scheme 0x%08x: %s", id, err.Error())
+		}
+
+		_, rest, err := readLengthPrefixed(signedData)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid digests in scheme 0x%08x: %s", id, err.Error())
+		}
+
+		certsData, _, err := readLengthPrefixed(rest)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid certificates in scheme 0x%08x: %s", id, err.Error())
+		}
+
+		var certs []*x509.Certificate
+		for len(certsData) != 0 {
+			var der []byte
+			if der, certsData, err = readLengthPrefixed(certsData); err != nil {
+				return nil, fmt.Errorf("Invalid certificate in scheme 0x%08x: %s", id, err.Error())
+			}
+
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, cert)
+		}
+		return certs, nil
+	}
+	return nil, nil
+}
+
+func readLengthPrefixed(data []byte) (value, rest []byte, err error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("not enough data for length prefix")
+	}
+
+	size := binary.LittleEndian.Uint32(data)
+	if uint64(size) > uint64(len(data)-4) {
+		return nil, nil, fmt.Errorf("length %d out of bounds", size)
+	}
+	return data[4 : 4+size], data[4+size:], nil
+}
+
+// Returns the ID-value pairs of the APK Signing Block, which is right before the central directory.
+func (a *APK) readSigningBlock() ([]byte, error) {
+	f := a.zip.zipFile
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// End of central directory record with max length comment
+	tailLen := int64(22 + 0xffff)
+	if tailLen > fi.Size() {
+		tailLen = fi.Size()
+	}
+
+	tail := make([]byte, tailLen)
+	if _, err := f.ReadAt(tail, fi.Size()-tailLen); err != nil {
+		return nil, err
+	}
+
+	eocd := bytes.LastIndex(tail, []byte{0x50, 0x4b, 0x05, 0x06})
+	if eocd == -1 || eocd+22 > len(tail) {
+		return nil, nil
+	}
+
+	cdOffset := int64(binary.LittleEndian.Uint32(tail[eocd+16:]))
+	if cdOffset < 24 || cdOffset > fi.Size() {
+		return nil, nil
+	}
+
+	footer := make([]byte, 24)
+	if _, err := f.ReadAt(footer, cdOffset-24); err != nil {
+		return nil, err
+	}
+
+	if string(footer[8:]) != apkSigBlockMagic {
+		return nil, nil
+	}
+
+	size := binary.LittleEndian.Uint64(footer)
+	if size < 24 || size > uint64(cdOffset-8) {
+		return nil, fmt.Errorf("Invalid APK Signing Block size: %d", size)
+	}
+
+	block := make([]byte, size-24)
+	if _, err := f.ReadAt(block, cdOffset-int64(size)); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
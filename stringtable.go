@@ -18,14 +18,16 @@ const (
 	stringFlagUtf8   = 0x00000100
 )
 
-type stringTable struct {
+// String pool (ResStringPool) of a binary XML or resources.arsc. Strings are decoded lazily,
+// on first access.
+type StringTable struct {
 	isUtf8        bool
 	stringOffsets []byte
 	data          []byte
 	cache         map[uint32]string
 }
 
-func parseStringTableWithChunk(r io.Reader) (res stringTable, err error) {
+func parseStringTableWithChunk(r io.Reader) (res StringTable, err error) {
 	id, _, totalLen, err := parseChunkHeader(r)
 	if err != nil {
 		return
@@ -39,17 +41,17 @@ func parseStringTableWithChunk(r io.Reader) (res stringTable, err error) {
 	return parseStringTable(&io.LimitedReader{R: r, N: int64(totalLen - chunkHeaderSize)})
 }
 
-func parseStringTable(r *io.LimitedReader) (stringTable, error) {
+func parseStringTable(r *io.LimitedReader) (StringTable, error) {
 	var err error
 	var stringCnt, stringOffset, flags uint32
-	var res stringTable
+	var res StringTable
 
 	if err := binary.Read(r, binary.LittleEndian, &stringCnt); err != nil {
 		return res, fmt.Errorf("error reading stringCnt: %s", err.Error())
 	}
 
-	// skip styles count
-	if _, err = io.CopyN(ioutil.Discard, r, 4); err != nil {
+	var styleCnt uint32
+	if err := binary.Read(r, binary.LittleEndian, &styleCnt); err != nil {
 		return res, fmt.Errorf("error reading styleCnt: %s", err.Error())
 	}
 
@@ -71,8 +73,8 @@ func parseStringTable(r *io.LimitedReader) (stringTable, error) {
 		return res, fmt.Errorf("error reading stringOffset: %s", err.Error())
 	}
 
-	// skip styles offset
-	if _, err = io.CopyN(ioutil.Discard, r, 4); err != nil {
+	var stylesOffset uint32
+	if err := binary.Read(r, binary.LittleEndian, &stylesOffset); err != nil {
 		return res, fmt.Errorf("error reading styleOffset: %s", err.Error())
 	}
 
@@ -95,26 +97,55 @@ func parseStringTable(r *io.LimitedReader) (stringTable, error) {
 		}
 	}
 
-	res.data = make([]byte, r.N)
+	// The styles data follows the string data, don't let strings read into it.
+	// Like Android, ignore the offset if there are no styles, obfuscators put garbage there.
+	dataLen := r.N
+	if styleCnt != 0 && stylesOffset != 0 {
+		if stylesOffset < stringOffset || int64(stylesOffset-stringOffset) > r.N {
+			return res, fmt.Errorf("Wrong styles offset %d (string offset %d)", stylesOffset, stringOffset)
+		}
+		dataLen = int64(stylesOffset - stringOffset)
+	}
+
+	res.data = make([]byte, dataLen)
 	if _, err := io.ReadFull(r, res.data); err != nil {
 		return res, fmt.Errorf("Failed to read string table data: %s", err.Error())
 	}
 
+	// skip styles data
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return res, fmt.Errorf("error reading styles data: %s", err.Error())
+	}
+
 	res.cache = make(map[uint32]string)
 	return res, nil
 }
 
-func (t *stringTable) parseString16(r io.Reader) (string, error) {
+func (t *StringTable) parseString16(r io.Reader) (string, error) {
+	buf, err := t.parseString16Units(r)
+	if err != nil {
+		return "", err
+	}
+
+	decoded := utf16.Decode(buf)
+	for len(decoded) != 0 && decoded[len(decoded)-1] == 0 {
+		decoded = decoded[:len(decoded)-1]
+	}
+
+	return string(decoded), nil
+}
+
+func (t *StringTable) parseString16Units(r io.Reader) ([]uint16, error) {
 	var strCharacters uint32
 	var strCharactersLow, strCharactersHigh uint16
 
 	if err := binary.Read(r, binary.LittleEndian, &strCharactersHigh); err != nil {
-		return "", fmt.Errorf("error reading string char count: %s", err.Error())
+		return nil, fmt.Errorf("error reading string char count: %s", err.Error())
 	}
 
 	if (strCharactersHigh & 0x8000) != 0 {
 		if err := binary.Read(r, binary.LittleEndian, &strCharactersLow); err != nil {
-			return "", fmt.Errorf("error reading string char count: %s", err.Error())
+			return nil, fmt.Errorf("error reading string char count: %s", err.Error())
 		}
 
 		strCharacters = (uint32(strCharactersHigh&0x7FFF) << 16) | uint32(strCharactersLow)
@@ -124,18 +155,12 @@ func (t *stringTable) parseString16(r io.Reader) (string, error) {
 
 	buf := make([]uint16, int64(strCharacters))
 	if err := binary.Read(r, binary.LittleEndian, &buf); err != nil {
-		return "", fmt.Errorf("error reading string : %s", err.Error())
+		return nil, fmt.Errorf("error reading string : %s", err.Error())
 	}
-
-	decoded := utf16.Decode(buf)
-	for len(decoded) != 0 && decoded[len(decoded)-1] == 0 {
-		decoded = decoded[:len(decoded)-1]
-	}
-
-	return string(decoded), nil
+	return buf, nil
 }
 
-func (t *stringTable) parseString8Len(r io.Reader) (int64, error) {
+func (t *StringTable) parseString8Len(r io.Reader) (int64, error) {
 	var strCharacters int64
 	var strCharactersLow, strCharactersHigh uint8
 
@@ -154,7 +179,7 @@ func (t *stringTable) parseString8Len(r io.Reader) (int64, error) {
 	return strCharacters, nil
 }
 
-func (t *stringTable) parseString8(r io.Reader) (string, error) {
+func (t *StringTable) parseString8(r io.Reader) (string, error) {
 	// Length of the string in UTF16
 	_, err := t.parseString8Len(r)
 	if err != nil {
@@ -182,7 +207,17 @@ func (t *stringTable) parseString8(r io.Reader) (string, error) {
 	return string(buf), nil
 }
 
-func (t *stringTable) get(idx uint32) (string, error) {
+// Returns the number of strings in the table.
+func (t *StringTable) Len() int {
+	return len(t.stringOffsets) / 4
+}
+
+// Returns the string at idx. 0xFFFFFFFF, which binary XML uses for no string, returns "".
+func (t *StringTable) Get(idx uint32) (string, error) {
+	return t.get(idx)
+}
+
+func (t *StringTable) get(idx uint32) (string, error) {
 	if idx == math.MaxUint32 {
 		return "", nil
 	} else if idx >= uint32(len(t.stringOffsets)/4) {
@@ -218,6 +253,99 @@ func (t *stringTable) get(idx uint32) (string, error) {
 	return res, nil
 }
 
-func (t *stringTable) isEmpty() bool {
+// One problem found by StringTable.Validate.
+type ValidationError struct {
+	Index  uint32
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("String %d: %s", e.Index, e.Reason)
+}
+
+// Checks all strings of the table: offsets must point into the string data, UTF-8 strings must
+// be valid UTF-8, UTF-16 strings must not have unpaired surrogates and no two indexes may have
+// the same string, which aapt never writes. Returns one ValidationError per problem.
+func (t *StringTable) Validate() ([]ValidationError, error) {
+	var res []ValidationError
+	seen := make(map[string]uint32)
+	cnt := uint32(len(t.stringOffsets) / 4)
+	for idx := uint32(0); idx < cnt; idx++ {
+		offset := binary.LittleEndian.Uint32(t.stringOffsets[4*idx:])
+		if offset >= uint32(len(t.data)) {
+			res = append(res, ValidationError{idx, fmt.Sprintf("offset %d is out of bounds (%d)", offset, len(t.data))})
+			continue
+		}
+
+		r := bytes.NewReader(t.data[offset:])
+
+		var str string
+		if t.isUtf8 {
+			var err error
+			if str, err = t.parseString8(r); err != nil {
+				res = append(res, ValidationError{idx, err.Error()})
+				continue
+			}
+		} else {
+			units, err := t.parseString16Units(r)
+			if err != nil {
+				res = append(res, ValidationError{idx, err.Error()})
+				continue
+			}
+
+			if i := unpairedSurrogate(units); i != -1 {
+				res = append(res, ValidationError{idx, fmt.Sprintf("unpaired surrogate 0x%04x at %d", units[i], i)})
+				continue
+			}
+
+			decoded := utf16.Decode(units)
+			for len(decoded) != 0 && decoded[len(decoded)-1] == 0 {
+				decoded = decoded[:len(decoded)-1]
+			}
+			str = string(decoded)
+		}
+
+		if first, prs := seen[str]; prs {
+			res = append(res, ValidationError{idx, fmt.Sprintf("same string as %d", first)})
+		} else {
+			seen[str] = idx
+		}
+	}
+	return res, nil
+}
+
+// Returns index of the first surrogate without its pair, or -1.
+func unpairedSurrogate(units []uint16) int {
+	for i := 0; i < len(units); i++ {
+		switch u := units[i]; {
+		case u >= 0xd800 && u < 0xdc00:
+			if i+1 >= len(units) || units[i+1] < 0xdc00 || units[i+1] >= 0xe000 {
+				return i
+			}
+			i++
+		case u >= 0xdc00 && u < 0xe000:
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *StringTable) isEmpty() bool {
 	return t.cache == nil
 }
+
+func (t *StringTable) clone() StringTable {
+	res := StringTable{
+		isUtf8:        t.isUtf8,
+		stringOffsets: append([]byte(nil), t.stringOffsets...),
+		data:          append([]byte(nil), t.data...),
+	}
+
+	if t.cache != nil {
+		res.cache = make(map[uint32]string, len(t.cache))
+		for k, v := range t.cache {
+			res.cache[k] = v
+		}
+	}
+	return res
+}
@@ -0,0 +1,154 @@
+package apkparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// UTF-16 string pool, with style spans after the string data like aapt writes them.
+type testStringPool struct {
+	strs         [][]uint16
+	styleCnt     uint32
+	styles       []byte
+	stylesOffset uint32 // if 0 and there are styles, the real offset
+}
+
+func (p *testStringPool) build() []byte {
+	le := binary.LittleEndian
+	var data bytes.Buffer
+	var offs []uint32
+	for _, s := range p.strs {
+		offs = append(offs, uint32(data.Len()))
+		binary.Write(&data, le, uint16(len(s)))
+		binary.Write(&data, le, s)
+		binary.Write(&data, le, uint16(0))
+	}
+	for data.Len()%4 != 0 {
+		data.WriteByte(0)
+	}
+
+	stringOffset := uint32(28 + 4*len(p.strs) + 4*int(p.styleCnt))
+	stylesOffset := p.stylesOffset
+	if stylesOffset == 0 && len(p.styles) != 0 {
+		stylesOffset = stringOffset + uint32(data.Len())
+	}
+
+	var out bytes.Buffer
+	binary.Write(&out, le, uint16(chunkStringTable))
+	binary.Write(&out, le, uint16(28))
+	binary.Write(&out, le, uint32(int(stringOffset)+data.Len()+len(p.styles)))
+	binary.Write(&out, le, [5]uint32{uint32(len(p.strs)), p.styleCnt, 0, stringOffset, stylesOffset})
+	binary.Write(&out, le, offs)
+	for i := uint32(0); i < p.styleCnt; i++ {
+		binary.Write(&out, le, uint32(0))
+	}
+	out.Write(data.Bytes())
+	out.Write(p.styles)
+	return out.Bytes()
+}
+
+func testUtf16(strs ...string) [][]uint16 {
+	var res [][]uint16
+	for _, s := range strs {
+		res = append(res, utf16.Encode([]rune(s)))
+	}
+	return res
+}
+
+func TestParseStringTableStyles(t *testing.T) {
+	// One <b> span over the first 5 characters of string 0, then the end markers.
+	var styles bytes.Buffer
+	binary.Write(&styles, binary.LittleEndian, []uint32{2, 0, 4, 0xffffffff, 0xffffffff, 0xffffffff})
+
+	tests := []struct {
+		name  string
+		pool  testStringPool
+		error string
+	}{
+		{"no styles", testStringPool{strs: testUtf16("hello", "world", "b")}, ""},
+		{"garbage offset without styles", testStringPool{strs: testUtf16("hello", "world", "b"), stylesOffset: 0xdeadbeef}, ""},
+		{"styles", testStringPool{strs: testUtf16("hello", "world", "b"), styleCnt: 1, styles: styles.Bytes()}, ""},
+		{"styles offset out of range", testStringPool{strs: testUtf16("hello", "world", "b"), styleCnt: 1,
+			styles: styles.Bytes(), stylesOffset: 0xdeadbeef}, "Wrong styles offset"},
+		{"styles offset before strings", testStringPool{strs: testUtf16("hello", "world", "b"), styleCnt: 1,
+			styles: styles.Bytes(), stylesOffset: 8}, "Wrong styles offset"},
+	}
+
+	for _, test := range tests {
+		tbl, err := parseStringTableWithChunk(bytes.NewReader(test.pool.build()))
+		if test.error != "" {
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("%s: expected error '%s', got %v", test.name, test.error, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+
+		for i, expected := range []string{"hello", "world", "b"} {
+			if str, err := tbl.Get(uint32(i)); str != expected || err != nil {
+				t.Errorf("%s: string %d is '%s' (%v), expected '%s'", test.name, i, str, err, expected)
+			}
+		}
+
+		if len(test.pool.styles) != 0 && bytes.Contains(tbl.data, test.pool.styles) {
+			t.Errorf("%s: styles are part of the string data", test.name)
+		}
+	}
+}
+
+func TestStringTableValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		strs     [][]uint16
+		expected []ValidationError
+	}{
+		{"valid", testUtf16("a", "b", "\U0001F600"), nil},
+		{"duplicate", testUtf16("a", "b", "a", "b", "a"), []ValidationError{{2, "same string as 0"}, {3, "same string as 1"}, {4, "same string as 0"}}},
+		{"unpaired high surrogate", [][]uint16{{'a', 0xd83d, 'b'}}, []ValidationError{{0, "unpaired surrogate 0xd83d at 1"}}},
+		{"high surrogate at end", [][]uint16{{'a'}, {'a', 0xd83d}}, []ValidationError{{1, "unpaired surrogate 0xd83d at 1"}}},
+		{"lone low surrogate", [][]uint16{{0xde00, 'a'}}, []ValidationError{{0, "unpaired surrogate 0xde00 at 0"}}},
+	}
+
+	for _, test := range tests {
+		pool := testStringPool{strs: test.strs}
+		tbl, err := parseStringTableWithChunk(bytes.NewReader(pool.build()))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+
+		res, err := tbl.Validate()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+		} else if len(res) != len(test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.name, res, test.expected)
+		} else {
+			for i := range res {
+				if res[i] != test.expected[i] {
+					t.Errorf("%s: got %v, expected %v", test.name, res[i], test.expected[i])
+				}
+			}
+		}
+	}
+}
+
+func TestStringTableValidateOffsets(t *testing.T) {
+	pool := testStringPool{strs: testUtf16("a", "b")}
+	data := pool.build()
+	binary.LittleEndian.PutUint32(data[28+4:], 0x1000)
+
+	tbl, err := parseStringTableWithChunk(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tbl.Validate()
+	if err != nil || len(res) != 1 || res[0].Index != 1 || !strings.Contains(res[0].Reason, "out of bounds") {
+		t.Errorf("got %v %v", res, err)
+	}
+}
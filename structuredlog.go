@@ -0,0 +1,148 @@
+package apkparser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returns an overview of the APK in sections "manifest", "resources", "security" and "size",
+// made of maps, slices, strings, numbers and bools, so it can be printed or encoded as JSON.
+// Security checks which fail are reported in the "errors" list of their section.
+func (a *APK) StructuredLog() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"manifest":  a.manifestLog(),
+		"resources": a.resourcesLog(),
+		"security":  a.securityLog(),
+		"size":      a.sizeLog(),
+	}, nil
+}
+
+func (a *APK) manifestLog() map[string]interface{} {
+	res := map[string]interface{}{
+		"package":          a.packageName(),
+		"versionCode":      a.manifest.attrOrEmpty("versionCode"),
+		"versionName":      a.manifest.attrOrEmpty("versionName"),
+		"targetSdkVersion": a.targetSdkVersion(),
+		"permissions":      a.usesPermissions(),
+	}
+
+	if usesSdk := a.manifest.child("uses-sdk"); usesSdk != nil {
+		if v, err := strconv.Atoi(usesSdk.attrOrEmpty("minSdkVersion")); err == nil {
+			res["minSdkVersion"] = v
+		}
+	}
+
+	components := make(map[string][]string)
+	for _, c := range a.components() {
+		components[c.Name] = append(components[c.Name], a.componentName(c))
+	}
+	res["components"] = components
+	return res
+}
+
+func (a *APK) resourcesLog() map[string]interface{} {
+	res := make(map[string]interface{})
+	if a.resourcesErr != nil {
+		res["error"] = a.resourcesErr.Error()
+	}
+	if a.resources == nil {
+		return res
+	}
+
+	var groupIds []int
+	for id := range a.resources.packages {
+		groupIds = append(groupIds, int(id))
+	}
+	sort.Ints(groupIds)
+
+	var packages []map[string]interface{}
+	for _, id := range groupIds {
+		group := a.resources.packages[uint32(id)]
+		types := make(map[string]int)
+		for typeId, typeList := range group.types {
+			for _, spec := range typeList {
+				name, err := spec.Package.typeStrings.get(uint32(typeId) - 1 - spec.Package.typeIdOffset)
+				if err == nil {
+					types[name] += len(spec.Entries)
+				}
+			}
+		}
+
+		packages = append(packages, map[string]interface{}{
+			"id":    group.Id,
+			"name":  group.Name,
+			"types": types,
+		})
+	}
+	res["packages"] = packages
+	return res
+}
+
+func (a *APK) securityLog() map[string]interface{} {
+	res := make(map[string]interface{})
+	var errs []string
+	addErr := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	res["debuggable"] = a.applicationBoolAttr("debuggable", false)
+	res["allowBackup"], _ = a.AllowBackup()
+	res["exportedActivitiesWithoutPermission"], _ = a.ExportedActivitiesWithoutPermission()
+	res["exportedServicesWithoutPermission"], _ = a.ExportedServicesWithoutPermission()
+	res["exportedReceiversWithoutPermission"], _ = a.ExportedReceiversWithoutPermission()
+	res["exportedProvidersWithoutPermission"], _ = a.ExportedProvidersWithoutPermission()
+
+	pinning, mechanisms, err := a.SSLPinningActive()
+	addErr(err)
+	res["sslPinning"] = pinning
+	res["sslPinningMechanisms"] = mechanisms
+
+	dynamicCode, err := a.UsesDynamicCode()
+	addErr(err)
+	res["dynamicCode"] = dynamicCode
+
+	algorithms, err := a.CryptoAlgorithms()
+	addErr(err)
+	res["cryptoAlgorithms"] = algorithms
+
+	if len(errs) != 0 {
+		res["errors"] = errs
+	}
+	return res
+}
+
+func (a *APK) sizeLog() map[string]interface{} {
+	res := make(map[string]interface{})
+	if fi, err := a.zip.zipFile.Stat(); err == nil {
+		res["fileSize"] = fi.Size()
+	}
+
+	// Only known for zips archive/zip can read, the fallback parser doesn't read the sizes.
+	compressed := make(map[string]uint64)
+	uncompressed := make(map[string]uint64)
+	for _, f := range a.zip.FilesOrdered {
+		if f.zipEntry == nil {
+			continue
+		}
+
+		category := "other"
+		switch {
+		case strings.HasSuffix(f.Name, ".dex"):
+			category = "dex"
+		case strings.HasPrefix(f.Name, "lib/"):
+			category = "native"
+		case strings.HasPrefix(f.Name, "res/"), f.Name == "resources.arsc":
+			category = "resources"
+		case strings.HasPrefix(f.Name, "assets/"):
+			category = "assets"
+		}
+		compressed[category] += f.zipEntry.CompressedSize64
+		uncompressed[category] += f.zipEntry.UncompressedSize64
+	}
+	res["compressed"] = compressed
+	res["uncompressed"] = uncompressed
+	return res
+}
@@ -0,0 +1,396 @@
+package apkparser
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Returns the entry of the value resource like ("string", "app_name") for the locale,
+// with references followed. The name may also be prefixed with the type, like "@string/app_name".
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) valueResourceEntry(typ, name string, locale language.Tag) (*ResourceEntry, error) {
+	if a.resources == nil {
+		return nil, ErrNotFound
+	}
+
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "@"), typ+"/")
+	id, err := a.resources.GetResourceId(typ, name)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	entry, err := a.resources.getEntryForLocale(id, locale)
+	for i := 0; err == nil && !entry.IsComplex() && entry.value.dataType == AttrTypeReference && i < 5; i++ {
+		entry, err = a.resources.getEntryForLocale(entry.value.data, locale)
+	}
+	return entry, err
+}
+
+// Follows references of a value in a complex entry, like items of an array pointing to strings.
+func (a *APK) resolveValue(v ResourceValue, locale language.Tag) (ResourceValue, error) {
+	for i := 0; v.dataType == AttrTypeReference && i < 5; i++ {
+		entry, err := a.resources.getEntryForLocale(v.data, locale)
+		if err != nil {
+			return v, err
+		} else if entry.IsComplex() {
+			return v, fmt.Errorf("Resource 0x%08x is not a simple value.", v.data)
+		}
+		v = entry.value
+	}
+	return v, nil
+}
+
+// ResTable_map names of plurals quantities
+var pluralQuantities = map[plural.Form]uint32{
+	plural.Other: 0x01000004,
+	plural.Zero:  0x01000005,
+	plural.One:   0x01000006,
+	plural.Two:   0x01000007,
+	plural.Few:   0x01000008,
+	plural.Many:  0x01000009,
+}
+
+// Returns the string of the <plurals> resource for the quantity in the locale, picked by the CLDR plural rules,
+// with the quantity formatted into it, like Android's Resources.getQuantityString(id, quantity, quantity).
+// Falls back to the "other" quantity if the resource doesn't have the one the rules pick, as Android does.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) PluralsResource(name string, quantity int, locale language.Tag) (string, error) {
+	entry, err := a.valueResourceEntry("plurals", name, locale)
+	if err != nil {
+		return "", err
+	} else if !entry.IsComplex() {
+		return "", fmt.Errorf("Resource %s is not plurals.", name)
+	} else if entry.mapErr != nil {
+		return "", fmt.Errorf("Invalid resource %s: %s", name, entry.mapErr.Error())
+	}
+
+	n := quantity
+	if n < 0 {
+		n = -n
+	}
+	form := plural.Cardinal.MatchPlural(locale, n, 0, 0, 0, 0)
+
+	var val *ResourceValue
+	for _, wanted := range []uint32{pluralQuantities[form], pluralQuantities[plural.Other]} {
+		for i := range entry.mapEntries {
+			if entry.mapEntries[i].name == wanted {
+				val = &entry.mapEntries[i].value
+				break
+			}
+		}
+		if val != nil {
+			break
+		}
+	}
+	if val == nil {
+		return "", fmt.Errorf("Resource %s has no quantity for %d.", name, quantity)
+	}
+
+	str, err := a.stringValue(*val, locale)
+	if err != nil {
+		return "", fmt.Errorf("Invalid quantity of resource %s: %s", name, err.Error())
+	}
+	return formatJavaString(str, quantity)
+}
+
+// Returns the string resource like "app_name" for the locale, formatted with args like
+// Android's Resources.getString(id, args...). Positional arguments like %1$s are supported.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) FormattedString(name string, locale language.Tag, args ...interface{}) (string, error) {
+	entry, err := a.valueResourceEntry("string", name, locale)
+	if err != nil {
+		return "", err
+	} else if entry.IsComplex() {
+		return "", fmt.Errorf("Resource %s is not a string.", name)
+	}
+
+	str, err := a.stringValue(entry.value, locale)
+	if err != nil {
+		return "", fmt.Errorf("Invalid resource %s: %s", name, err.Error())
+	}
+	return formatJavaString(str, args...)
+}
+
+// Resolves the value and returns it if it is a string.
+func (a *APK) stringValue(v ResourceValue, locale language.Tag) (string, error) {
+	v, err := a.resolveValue(v, locale)
+	if err != nil {
+		return "", err
+	} else if v.dataType != AttrTypeString {
+		return "", fmt.Errorf("Value of type 0x%02x is not a string.", v.dataType)
+	}
+	return v.globalStringTable.get(v.data)
+}
+
+// %[argument_index$|<][flags][width][.precision]conversion, without date/time conversions
+var javaFormatSpecRegexp = regexp.MustCompile(`%(\d+\$|<)?([-#+ 0,(]*)(\d*)(\.\d+)?([a-zA-Z%])`)
+
+// Formats the args like Java's String.format, which Android uses for string resources. Conversions
+// are done by the fmt package, so these are not exactly the same, e.g. for %e. Flags ',' and '(' are ignored.
+// Unlike fmt.Sprintf, arguments not used by the format are not reported, but like Java,
+// missing arguments and arguments of a type the conversion doesn't accept are errors.
+func formatJavaString(format string, args ...interface{}) (string, error) {
+	var res bytes.Buffer
+	last, ordinary, prev := 0, 0, -1
+	for _, m := range javaFormatSpecRegexp.FindAllStringSubmatchIndex(format, -1) {
+		res.WriteString(format[last:m[0]])
+		last = m[1]
+
+		group := func(i int) string {
+			if m[2*i] == -1 {
+				return ""
+			}
+			return format[m[2*i]:m[2*i+1]]
+		}
+		spec, index, flags, conv := format[m[0]:m[1]], group(1), group(2), group(5)
+
+		switch conv {
+		case "%":
+			res.WriteByte('%')
+			continue
+		case "n":
+			res.WriteByte('\n')
+			continue
+		}
+
+		argIdx := ordinary
+		switch {
+		case index == "<":
+			argIdx = prev
+		case index != "":
+			n, _ := strconv.Atoi(strings.TrimSuffix(index, "$"))
+			argIdx = n - 1
+		default:
+			ordinary++
+		}
+		if argIdx < 0 || argIdx >= len(args) {
+			return "", fmt.Errorf("Missing argument for format specifier '%s'.", spec)
+		}
+		prev = argIdx
+		arg := args[argIdx]
+
+		kind := reflect.ValueOf(arg).Kind()
+		isInt := kind >= reflect.Int && kind <= reflect.Uint64
+		isFloat := kind == reflect.Float32 || kind == reflect.Float64
+
+		verb, typeOk := conv, true
+		switch conv {
+		case "s", "S":
+			verb = "v"
+		case "b", "B":
+			if _, ok := arg.(bool); !ok {
+				arg = arg != nil
+			}
+			verb = "t"
+		case "d", "o", "x", "X":
+			typeOk = isInt
+		case "e", "E", "f", "g", "G":
+			typeOk = isFloat
+		case "c", "C":
+			// Characters are passed as runes or code points.
+			verb, typeOk = "c", isInt
+		default:
+			return "", fmt.Errorf("Unsupported format specifier '%s'.", spec)
+		}
+
+		// Like Java, null is formatted as "null" by any conversion but %b.
+		if arg == nil {
+			arg, verb, typeOk = "null", "s", true
+		}
+		if !typeOk {
+			return "", fmt.Errorf("Format specifier '%s' doesn't accept argument %d of type %T.", spec, argIdx+1, arg)
+		}
+
+		flags = strings.NewReplacer(",", "", "(", "").Replace(flags)
+		out := fmt.Sprintf("%"+flags+group(3)+group(4)+verb, arg)
+		if conv == "S" || conv == "B" || conv == "C" {
+			out = strings.ToUpper(out)
+		}
+		res.WriteString(out)
+	}
+	res.WriteString(format[last:])
+	return res.String(), nil
+}
+
+// Returns the items of complex entry of array type, <string-array>, <integer-array> or <array>,
+// which are all compiled to the "array" resource type.
+func (a *APK) arrayEntries(name string, locale language.Tag) ([]resourceMapEntry, error) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "@"), "string-array/")
+	entry, err := a.valueResourceEntry("array", name, locale)
+	if err != nil {
+		return nil, err
+	} else if !entry.IsComplex() {
+		return nil, fmt.Errorf("Resource %s is not an array.", name)
+	} else if entry.mapErr != nil {
+		return nil, fmt.Errorf("Invalid resource %s: %s", name, entry.mapErr.Error())
+	}
+	return entry.mapEntries, nil
+}
+
+// Returns the items of the <string-array> resource for the locale, in order, with references resolved.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) StringArrayResource(name string, locale language.Tag) ([]string, error) {
+	items, err := a.arrayEntries(name, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(items))
+	for i := range items {
+		str, err := a.stringValue(items[i].value, locale)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid item %d of resource %s: %s", i, name, err.Error())
+		}
+		res = append(res, str)
+	}
+	return res, nil
+}
+
+// Returns the items of the <array>, <integer-array> or <string-array> resource for the locale, in order.
+// References are followed, so the Type of the values is the type of the final value, e.g. AttrTypeString
+// for @string/ items and for file resources like @drawable/icon, which is the path to the file.
+// References to complex resources like other arrays are kept as AttrTypeReference.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) TypedArrayResource(name string, locale language.Tag) ([]ResourceValue, error) {
+	items, err := a.arrayEntries(name, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]ResourceValue, 0, len(items))
+	for i := range items {
+		v, err := a.resolveValue(items[i].value, locale)
+		if err != nil {
+			v = items[i].value
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// Returns the value of the simple (non-complex) resource like ("integer", "max_items") for the locale.
+func (a *APK) simpleValueResource(typ, name string, locale language.Tag) (ResourceValue, error) {
+	entry, err := a.valueResourceEntry(typ, name, locale)
+	if err != nil {
+		return ResourceValue{}, err
+	} else if entry.IsComplex() {
+		return ResourceValue{}, fmt.Errorf("Resource %s is not a simple value.", name)
+	}
+	return entry.value, nil
+}
+
+// Returns the value of the <integer> resource for the locale, stored as AttrTypeIntDec or AttrTypeIntHex.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) IntegerResource(name string, locale language.Tag) (int32, error) {
+	v, err := a.simpleValueResource("integer", name, locale)
+	if err != nil {
+		return 0, err
+	} else if v.dataType != AttrTypeIntDec && v.dataType != AttrTypeIntHex {
+		return 0, fmt.Errorf("Resource %s is not an integer (type 0x%02x).", name, v.dataType)
+	}
+	return int32(v.data), nil
+}
+
+// Returns the value of the <bool> resource for the locale, stored as AttrTypeIntBool.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) BoolResource(name string, locale language.Tag) (bool, error) {
+	v, err := a.simpleValueResource("bool", name, locale)
+	if err != nil {
+		return false, err
+	} else if v.dataType != AttrTypeIntBool {
+		return false, fmt.Errorf("Resource %s is not a bool (type 0x%02x).", name, v.dataType)
+	}
+	return v.data != 0, nil
+}
+
+// Unit of a dimension resource, see APK.DimensionResource.
+type ResUnit int
+
+const (
+	ResUnitPx ResUnit = iota
+	ResUnitDp
+	ResUnitSp
+	ResUnitPt
+	ResUnitIn
+	ResUnitMm
+)
+
+var resUnitNames = []string{"px", "dp", "sp", "pt", "in", "mm"}
+
+func (u ResUnit) String() string {
+	if u < 0 || int(u) >= len(resUnitNames) {
+		return fmt.Sprintf("ResUnit(%d)", int(u))
+	}
+	return resUnitNames[u]
+}
+
+// Radix multipliers of the complex values of dimensions and fractions
+var complexRadixMults = []float32{
+	1.0 / (1 << 8),
+	1.0 / (1 << 15),
+	1.0 / (1 << 23),
+	1.0 / (1 << 31),
+}
+
+// Decodes the value of dimension or fraction: 24 bit signed mantissa, 2 bit radix and 4 bit unit.
+func complexToFloat(data uint32) float32 {
+	return float32(int32(data&0xffffff00)) * complexRadixMults[(data>>4)&0x03]
+}
+
+// Returns the value and unit of the <dimen> resource for the locale, e.g. 16 and ResUnitDp for "16dp".
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) DimensionResource(name string, locale language.Tag) (float32, ResUnit, error) {
+	v, err := a.simpleValueResource("dimen", name, locale)
+	if err != nil {
+		return 0, 0, err
+	} else if v.dataType != AttrTypeDimension {
+		return 0, 0, fmt.Errorf("Resource %s is not a dimension (type 0x%02x).", name, v.dataType)
+	}
+
+	unit := ResUnit(v.data & 0x0f)
+	if int(unit) >= len(resUnitNames) {
+		return 0, 0, fmt.Errorf("Resource %s has unknown dimension unit %d.", name, int(unit))
+	}
+	return complexToFloat(v.data), unit, nil
+}
+
+// What a fraction resource is relative to, see APK.FractionResource.
+type FractionBase int
+
+const (
+	FractionBaseSelf   FractionBase = iota // "50%", fraction of the element's own size
+	FractionBaseParent                     // "50%p", fraction of the parent's size
+)
+
+// Returns the value and base of the <fraction> resource for the locale, e.g. 0.5 and FractionBaseParent for "50%p".
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) FractionResource(name string, locale language.Tag) (float32, FractionBase, error) {
+	v, err := a.simpleValueResource("fraction", name, locale)
+	if err != nil {
+		return 0, 0, err
+	} else if v.dataType != AttrTypeFraction {
+		return 0, 0, fmt.Errorf("Resource %s is not a fraction (type 0x%02x).", name, v.dataType)
+	}
+
+	base := FractionBase(v.data & 0x0f)
+	if base != FractionBaseSelf && base != FractionBaseParent {
+		return 0, 0, fmt.Errorf("Resource %s has unknown fraction base %d.", name, int(base))
+	}
+	return complexToFloat(v.data), base, nil
+}
@@ -0,0 +1,52 @@
+package apkparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatJavaString(t *testing.T) {
+	tests := []struct {
+		format   string
+		args     []interface{}
+		expected string
+		error    string
+	}{
+		{"plain", nil, "plain", ""},
+		{"%s has %d apples", []interface{}{"Anna", 3}, "Anna has 3 apples", ""},
+		{"%2$s, %1$s", []interface{}{"world", "Hello"}, "Hello, world", ""},
+		{"%1$s %1$S", []interface{}{"abc"}, "abc ABC", ""},
+		{"%d %<x %<o", []interface{}{255}, "255 ff 377", ""},
+		{"%2$d %<X %s", []interface{}{"a", 10}, "10 A a", ""},
+		{"line%nnext", nil, "line\nnext", ""},
+		{"100%% of %d", []interface{}{5}, "100% of 5", ""},
+		{"%5d|%-4s|%05.2f", []interface{}{42, "ab", 3.14159}, "   42|ab  |03.14", ""},
+		{"%,d", []interface{}{1234}, "1234", ""},
+		{"%b %B %b", []interface{}{true, "x", nil}, "true TRUE false", ""},
+		{"%c%C", []interface{}{'a', 'b'}, "aB", ""},
+		{"%s %d", []interface{}{nil, nil}, "null null", ""},
+		{"%x", []interface{}{uint8(0xab)}, "ab", ""},
+
+		{"%s and %s", []interface{}{"one"}, "", "Missing argument for format specifier '%s'"},
+		{"%3$s", []interface{}{"one", "two"}, "", "Missing argument for format specifier '%3$s'"},
+		{"%<s", []interface{}{"one"}, "", "Missing argument for format specifier '%<s'"},
+		{"%0$s", []interface{}{"one"}, "", "Missing argument"},
+		{"%d", []interface{}{"five"}, "", "Format specifier '%d' doesn't accept argument 1 of type string"},
+		{"%x", []interface{}{1.5}, "", "doesn't accept argument 1 of type float64"},
+		{"%f", []interface{}{1}, "", "Format specifier '%f' doesn't accept argument 1 of type int"},
+		{"%s %2$e", []interface{}{"a", "b"}, "", "Format specifier '%2$e' doesn't accept argument 2 of type string"},
+		{"%c", []interface{}{"a"}, "", "doesn't accept"},
+		{"%t", []interface{}{1}, "", "Unsupported format specifier '%t'"},
+	}
+
+	for _, test := range tests {
+		res, err := formatJavaString(test.format, test.args...)
+		if test.error != "" {
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("'%s': expected error '%s', got '%s' %v", test.format, test.error, res, err)
+			}
+		} else if err != nil || res != test.expected {
+			t.Errorf("'%s': got '%s' %v, expected '%s'", test.format, res, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package apkparser
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	watchFaceFormatVersion = "com.google.wear.watchface.format.version"
+	watchFacePreview       = "com.google.android.wearable.watchface.preview"
+	watchFaceConfigAction  = "com.google.android.wearable.watchface.wearableConfigurationAction"
+	watchFaceCategory      = "com.google.android.wearable.watchface.category.WATCH_FACE"
+)
+
+// Describes the Wear OS watch face in an APK.
+type WatchFaceInfo struct {
+	// "wff" for the XML based Watch Face Format, "legacy" for watch faces implemented
+	// as a WallpaperService.
+	Format string
+	// Resolved preview drawable, e.g. res/drawable/preview.png. Might be empty.
+	PreviewResource string
+	// Number of <ComplicationSlot> elements, only counted for the Watch Face Format.
+	ComplicationSlots int
+}
+
+// Returns information about the watch face, or ErrNotFound if the APK isn't a Wear OS watch face.
+func (a *APK) WatchFaceInfo() (*WatchFaceInfo, error) {
+	app := a.manifest.child("application")
+	if app == nil {
+		return nil, ErrNotFound
+	}
+
+	for _, prop := range app.children("property") {
+		if prop.attrOrEmpty("name") != watchFaceFormatVersion {
+			continue
+		}
+
+		info := &WatchFaceInfo{Format: "wff"}
+		info.PreviewResource, _ = elementMetaData(app, watchFacePreview)
+
+		if data, err := a.readFile("res/raw/watchface.xml"); err == nil {
+			info.ComplicationSlots = countXmlElements(string(data), "ComplicationSlot")
+		}
+		return info, nil
+	}
+
+	for _, svc := range a.watchFaceServices() {
+		info := &WatchFaceInfo{Format: "legacy"}
+		info.PreviewResource, _ = elementMetaData(svc, watchFacePreview)
+		return info, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Returns the <service> elements implementing legacy watch faces.
+func (a *APK) watchFaceServices() []*xmlElement {
+	app := a.manifest.child("application")
+	if app == nil {
+		return nil
+	}
+
+	var res []*xmlElement
+	for _, svc := range app.children("service") {
+		_, hasPreview := elementMetaData(svc, watchFacePreview)
+		_, hasConfig := elementMetaData(svc, watchFaceConfigAction)
+		if hasPreview || hasConfig {
+			res = append(res, svc)
+			continue
+		}
+
+	filterLoop:
+		for _, filter := range svc.children("intent-filter") {
+			for _, cat := range filter.children("category") {
+				if cat.attrOrEmpty("name") == watchFaceCategory {
+					res = append(res, svc)
+					break filterLoop
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Counts elements with this local name in a text XML document. Stops counting at the first
+// syntax error.
+func countXmlElements(doc, name string) int {
+	count := 0
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return count
+		}
+
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == name {
+			count++
+		}
+	}
+}
+
+// Wear OS tile, implemented by a TileService.
+type TileInfo struct {
+	Name  string
+	Label string
+	// Resolved preview drawable, e.g. res/drawable/tile_preview.png. Might be empty.
+	PreviewResource string
+}
+
+// Returns the Wear OS tiles, which are services handling androidx.wear.tiles.action.BIND_TILE_PROVIDER.
+func (a *APK) TileInfo() ([]TileInfo, error) {
+	app := a.manifest.child("application")
+	if app == nil {
+		return nil, nil
+	}
+
+	var res []TileInfo
+	for _, svc := range app.children("service") {
+		if !hasIntentFilterAction(svc, "androidx.wear.tiles.action.BIND_TILE_PROVIDER") {
+			continue
+		}
+
+		tile := TileInfo{
+			Name:  a.componentName(svc),
+			Label: svc.attrOrEmpty("label"),
+		}
+		tile.PreviewResource, _ = elementMetaData(svc, "androidx.wear.tiles.PREVIEW")
+		res = append(res, tile)
+	}
+	return res, nil
+}
+
+// Returns the requested permissions which give access to health data: body sensors,
+// activity recognition, Health Services and Health Connect permissions.
+func (a *APK) HealthServicesPermissions() ([]string, error) {
+	var res []string
+	for _, perm := range a.usesPermissions() {
+		switch {
+		case perm == "android.permission.BODY_SENSORS",
+			perm == "android.permission.BODY_SENSORS_BACKGROUND",
+			perm == "android.permission.ACTIVITY_RECOGNITION",
+			strings.HasPrefix(perm, "com.google.android.wearable.healthservices."),
+			strings.HasPrefix(perm, "android.permission.health."):
+			res = append(res, perm)
+		}
+	}
+	return res, nil
+}
+
+// Returns the number of complications of the watch face. For the Watch Face Format, these are
+// <ComplicationSlot>s in res/raw/watchface.xml. For legacy watch faces, <complication> (or
+// <ComplicationSlot>) elements in the XML resources referenced by the watch face service's
+// wearableConfigurationAction or androidx.wear.watchface.XmlSchemaAndComplicationSlotsDefinition meta-data.
+//
+// Returns ErrNotFound if the APK isn't a Wear OS watch face.
+func (a *APK) WatchFaceComplications() (int, error) {
+	info, err := a.WatchFaceInfo()
+	if err != nil {
+		return 0, err
+	} else if info.Format == "wff" {
+		return info.ComplicationSlots, nil
+	}
+
+	count := 0
+	for _, svc := range a.watchFaceServices() {
+		for _, name := range []string{watchFaceConfigAction, "androidx.wear.watchface.XmlSchemaAndComplicationSlotsDefinition"} {
+			path, prs := elementMetaData(svc, name)
+			if !prs || !strings.HasPrefix(path, "res/") || a.zip.File[path] == nil {
+				continue
+			}
+
+			root, err := a.parseXml(path)
+			if err != nil {
+				return 0, err
+			}
+			count += len(root.findAll("complication")) + len(root.findAll("ComplicationSlot"))
+		}
+	}
+	return count, nil
+}
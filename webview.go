@@ -0,0 +1,54 @@
+package apkparser
+
+// WebView configuration found in the app's code. The setting fields are true if the app calls
+// the respective WebSettings setter anywhere, the argument value is not checked.
+type WebViewUsage struct {
+	HasWebView                       bool
+	JavaScriptEnabled                bool
+	AllowFileAccess                  bool
+	AllowFileAccessFromFileURLs      bool
+	AllowUniversalAccessFromFileURLs bool
+}
+
+// Returns the WebView usage, detected from references to android.webkit classes in the dex files.
+func (a *APK) WebViewUsage() (WebViewUsage, error) {
+	var res WebViewUsage
+
+	files, err := a.dexFiles()
+	if err != nil {
+		return res, err
+	}
+
+	for _, d := range files {
+		for i := range d.classes {
+			if sup := d.classes[i].Superclass; sup < uint32(len(d.types)) && d.types[sup] == "Landroid/webkit/WebViewClient;" {
+				res.HasWebView = true
+			}
+		}
+	}
+
+	if !res.HasWebView {
+		if res.HasWebView, err = a.hasDexClass("android.webkit.WebView"); err != nil {
+			return res, err
+		}
+	}
+
+	settings := []struct {
+		method string
+		target *bool
+	}{
+		{"setJavaScriptEnabled", &res.JavaScriptEnabled},
+		{"setAllowFileAccess", &res.AllowFileAccess},
+		{"setAllowFileAccessFromFileURLs", &res.AllowFileAccessFromFileURLs},
+		{"setAllowUniversalAccessFromFileURLs", &res.AllowUniversalAccessFromFileURLs},
+	}
+
+	err = a.walkMethodsCode(func(d *dexFile, code *dexMethodCode) {
+		for _, s := range settings {
+			if !*s.target && d.invokes(code, "Landroid/webkit/WebSettings;", s.method) {
+				*s.target = true
+			}
+		}
+	})
+	return res, err
+}
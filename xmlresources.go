@@ -0,0 +1,415 @@
+package apkparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returns ZIP paths of the binary XML resources: every res/**/*.xml file but those in res/raw/,
+// which are stored as they are. That is layouts, menus, vector drawables, XML configs,
+// preferences, navigation graphs and so on.
+func (a *APK) ResourceXMLFiles() ([]string, error) {
+	var res []string
+	for _, f := range a.zip.FilesOrdered {
+		if !f.IsDir && strings.HasPrefix(f.Name, "res/") && strings.HasSuffix(f.Name, ".xml") &&
+			!strings.HasPrefix(f.Name, "res/raw/") && !strings.HasPrefix(f.Name, "res/raw-") {
+			res = append(res, f.Name)
+		}
+	}
+	return res, nil
+}
+
+// Screen of a Jetpack Navigation graph, see APK.NavigationGraph.
+type NavDestination struct {
+	ID    uint32
+	Label string
+	// Class name of the fragment, activity or dialog, empty for nested graphs.
+	ClassName string
+	Actions   []NavAction
+}
+
+// Action of NavDestination, which navigates to another destination.
+type NavAction struct {
+	ID          uint32
+	Destination uint32
+}
+
+// Returns the destinations (<fragment>, <activity>, <dialog>, nested <navigation> and custom ones)
+// from all navigation graphs in res/navigation/.
+//
+// Files which fail to parse don't stop the search, the destinations from the other files are
+// returned together with MultiError of the failures.
+func (a *APK) NavigationGraph() ([]NavDestination, error) {
+	res := []NavDestination{}
+	var errs MultiError
+	files, _ := a.ResourceXMLFiles()
+	for _, name := range files {
+		if !strings.HasPrefix(name, "res/navigation/") && !strings.HasPrefix(name, "res/navigation-") {
+			continue
+		}
+
+		// Unresolved, ids would become their (usually empty) values.
+		root, err := a.parseXmlWithResources(name, nil)
+		if err != nil {
+			if ctxErr := a.ctxErr(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			errs = append(errs, fmt.Errorf("%s: %s", name, err.Error()))
+			continue
+		}
+
+		var walk func(el *xmlElement)
+		walk = func(el *xmlElement) {
+			for _, c := range el.Children {
+				switch c.Name {
+				case "action", "argument", "deepLink", "include":
+					continue
+				}
+
+				dest := NavDestination{
+					ID:        referenceId(c.attrOrEmpty("id")),
+					Label:     a.resolveReference(c.attrOrEmpty("label")),
+					ClassName: a.className(c.attrOrEmpty("name")),
+				}
+				for _, act := range c.children("action") {
+					dest.Actions = append(dest.Actions, NavAction{
+						ID:          referenceId(act.attrOrEmpty("id")),
+						Destination: referenceId(act.attrOrEmpty("destination")),
+					})
+				}
+				res = append(res, dest)
+
+				if c.Name == "navigation" {
+					walk(c)
+				}
+			}
+		}
+		walk(root)
+	}
+
+	if len(errs) != 0 {
+		return res, errs
+	}
+	return res, nil
+}
+
+// Node of a preference hierarchy, see APK.PreferenceScreens. Screens and categories have
+// children, preferences like CheckBoxPreference don't.
+type PreferenceGroup struct {
+	// Element name, e.g. "PreferenceScreen", "EditTextPreference" or "androidx.preference.SwitchPreferenceCompat".
+	Type     string
+	Key      string
+	Title    string
+	Summary  string
+	Children []PreferenceGroup
+}
+
+// Returns the preference hierarchies from XML resources in res/xml/ with <PreferenceScreen> root
+// (also androidx.preference.PreferenceScreen and the like). Other XML files there are skipped.
+// Preferences with empty Title have no label for accessibility services.
+//
+// Files which fail to parse don't stop the search, the hierarchies from the other files are
+// returned together with MultiError of the failures.
+func (a *APK) PreferenceScreens() ([]PreferenceGroup, error) {
+	var res []PreferenceGroup
+	var errs MultiError
+	files, _ := a.ResourceXMLFiles()
+	for _, name := range files {
+		if !strings.HasPrefix(name, "res/xml/") && !strings.HasPrefix(name, "res/xml-") {
+			continue
+		}
+
+		root, err := a.parseXml(name)
+		if err != nil {
+			if ctxErr := a.ctxErr(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			errs = append(errs, fmt.Errorf("%s: %s", name, err.Error()))
+			continue
+		}
+
+		if root.Name == "PreferenceScreen" || strings.HasSuffix(root.Name, ".PreferenceScreen") {
+			res = append(res, preferenceGroup(root))
+		}
+	}
+
+	if len(errs) != 0 {
+		return res, errs
+	}
+	return res, nil
+}
+
+func preferenceGroup(el *xmlElement) PreferenceGroup {
+	res := PreferenceGroup{
+		Type:    el.Name,
+		Key:     el.attrOrEmpty("key"),
+		Title:   el.attrOrEmpty("title"),
+		Summary: el.attrOrEmpty("summary"),
+	}
+
+	for _, c := range el.Children {
+		// <intent> and <extra> configure the preference, they are not preferences
+		if c.Name != "intent" && c.Name != "extra" {
+			res.Children = append(res.Children, preferenceGroup(c))
+		}
+	}
+	return res
+}
+
+// Returns the ZIP path of the file referenced by manifest attribute value val, which is the path
+// if the reference was resolved, "@" and the hex id if it was not, or resource name like "@xml/file_paths".
+//
+// Returns ErrNotFound if the file is not in the zip.
+func (a *APK) referencedFilePath(val string) (string, error) {
+	if a.zip.File[val] != nil {
+		return val, nil
+	}
+
+	var path string
+	if id := referenceId(val); id != 0 {
+		if a.resources == nil {
+			return "", ErrNotFound
+		}
+
+		entry, err := a.resources.GetResourceEntry(id)
+		for i := 0; err == nil && entry.value.dataType == AttrTypeReference && i < 5; i++ {
+			entry, err = a.resources.GetResourceEntry(entry.value.data)
+		}
+		if err != nil || entry.value.dataType != AttrTypeString {
+			return "", ErrNotFound
+		}
+		path = entry.value.String()
+	} else if strings.IndexByte(val, '/') != -1 && !strings.HasPrefix(val, "res/") {
+		var err error
+		if path, err = a.resourceFilePath(val); err != nil {
+			return "", err
+		}
+	}
+
+	if path == "" || a.zip.File[path] == nil {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
+
+// Returns the ZIP path of file resource like "xml/network_security_config". Without resources.arsc,
+// it guesses the usual res/<type>/<name>.xml path.
+func (a *APK) resourceFilePath(resourceName string) (string, error) {
+	idx := strings.IndexByte(resourceName, '/')
+	if idx == -1 {
+		return "", fmt.Errorf("Invalid resource name '%s', expected type/name.", resourceName)
+	}
+	typ, name := strings.TrimPrefix(resourceName[:idx], "@"), resourceName[idx+1:]
+
+	if a.resources == nil {
+		path := "res/" + typ + "/" + name + ".xml"
+		if a.zip.File[path] == nil {
+			return "", ErrNotFound
+		}
+		return path, nil
+	}
+
+	id, err := a.resources.GetResourceId(typ, name)
+	if err != nil {
+		return "", ErrNotFound
+	}
+
+	entry, err := a.resources.GetResourceEntry(id)
+	for i := 0; err == nil && entry.value.dataType == AttrTypeReference && i < 5; i++ {
+		entry, err = a.resources.GetResourceEntry(entry.value.data)
+	}
+	if err != nil {
+		return "", err
+	} else if entry.value.dataType != AttrTypeString {
+		return "", fmt.Errorf("Resource %s is not a file.", resourceName)
+	}
+	return entry.value.String(), nil
+}
+
+// Returns the binary XML of the resource like "xml/network_security_config", as it is stored in the APK.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) RawXMLResource(resourceName string) ([]byte, error) {
+	path, err := a.resourceFilePath(resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := a.readFile(path)
+	if err == os.ErrNotExist {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Returns the resource like "xml/network_security_config" as indented text XML, with references
+// resolved through resources.arsc. The binary XML is decoded by ParseManifest, which can
+// decode any binary XML, not just the manifest.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) TextXMLResource(resourceName string) (string, error) {
+	data, err := a.RawXMLResource(resourceName)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "    ")
+	if err := ParseManifest(bytes.NewReader(data), enc, a.resources); err != nil {
+		return "", fmt.Errorf("Failed to parse %s: %s", resourceName, err.Error())
+	}
+	return buf.String(), nil
+}
+
+// Item of a color state list, see APK.ColorStateList.
+type ColorStateEntry struct {
+	// States without the "state_" prefix, like "pressed" or "enabled". States which must not be set
+	// (state_enabled="false") are prefixed with "!". Empty for the default item.
+	States []string
+	// With android:alpha already applied.
+	Color color.NRGBA
+}
+
+// Returns the items of a color state list resource like "color/button_text" (res/color/button_text.xml),
+// in order, as Android picks the first item matching the current state.
+// Items with a color which isn't a plain color value, like a nested color state list, are skipped.
+//
+// Returns ErrNotFound if there is no such resource.
+func (a *APK) ColorStateList(resourceName string) ([]ColorStateEntry, error) {
+	path, err := a.resourceFilePath(resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := a.parseXml(path)
+	if err != nil {
+		return nil, err
+	} else if root.Name != "selector" {
+		return nil, fmt.Errorf("Resource %s is not a color state list.", resourceName)
+	}
+
+	res := []ColorStateEntry{}
+	for _, item := range root.children("item") {
+		val, prs := item.attr("color")
+		if !prs {
+			continue
+		}
+
+		c, ok := parseColor(val)
+		if !ok {
+			continue
+		}
+
+		if alpha, err := strconv.ParseFloat(item.attrOrEmpty("alpha"), 32); err == nil && alpha >= 0 && alpha <= 1 {
+			c.A = uint8(float64(c.A)*alpha + 0.5)
+		}
+
+		entry := ColorStateEntry{States: []string{}, Color: c}
+		for _, attr := range item.Attrs {
+			if !strings.HasPrefix(attr.Name.Local, "state_") {
+				continue
+			}
+
+			state := strings.TrimPrefix(attr.Name.Local, "state_")
+			if attr.Value == "false" {
+				state = "!" + state
+			}
+			entry.States = append(entry.States, state)
+		}
+		res = append(res, entry)
+	}
+	return res, nil
+}
+
+// Parses the color as ParseManifest outputs it: #rgb, #argb, #rrggbb and #aarrggbb from resolved
+// references, decimal ARGB integer for values stored directly in the binary XML.
+func parseColor(val string) (color.NRGBA, bool) {
+	var argb uint64
+	var err error
+	if strings.HasPrefix(val, "#") {
+		hex := val[1:]
+		switch len(hex) {
+		case 3, 4:
+			var expanded []byte
+			for i := range hex {
+				expanded = append(expanded, hex[i], hex[i])
+			}
+			hex = string(expanded)
+		case 6, 8:
+		default:
+			return color.NRGBA{}, false
+		}
+		if len(hex) == 6 {
+			hex = "ff" + hex
+		}
+
+		if argb, err = strconv.ParseUint(hex, 16, 32); err != nil {
+			return color.NRGBA{}, false
+		}
+	} else {
+		v, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		argb = uint64(uint32(v))
+	}
+
+	return color.NRGBA{
+		A: uint8(argb >> 24),
+		R: uint8(argb >> 16),
+		G: uint8(argb >> 8),
+		B: uint8(argb),
+	}, true
+}
+
+// Returns sorted unique names of file resources of the type, e.g. "fade_in" for res/anim/fade_in.xml
+// and res/anim-v21/fade_in.xml.
+func (a *APK) fileResourceNames(typ string) []string {
+	res := []string{}
+	seen := make(map[string]bool)
+	for _, f := range a.zip.FilesOrdered {
+		if f.IsDir || !strings.HasPrefix(f.Name, "res/") {
+			continue
+		}
+
+		parts := strings.Split(f.Name[len("res/"):], "/")
+		if len(parts) != 2 || (parts[0] != typ && !strings.HasPrefix(parts[0], typ+"-")) {
+			continue
+		}
+
+		name := parts[1]
+		if idx := strings.IndexByte(name, '.'); idx != -1 {
+			name = name[:idx]
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Returns names of the property animation resources in res/animator/, <objectAnimator>,
+// <valueAnimator> and <set>. The files are not parsed.
+func (a *APK) AnimatorResources() ([]string, error) {
+	return a.fileResourceNames("animator"), nil
+}
+
+// Returns names of the Transition Framework resources in res/transition/ (API 21+), which define
+// activity, fragment and shared element transitions. The files are not parsed.
+func (a *APK) TransitionResources() ([]string, error) {
+	return a.fileResourceNames("transition"), nil
+}
+
+// Returns names of the custom interpolator resources in res/interpolator/. The files are not parsed.
+func (a *APK) InterpolatorResources() ([]string, error) {
+	return a.fileResourceNames("interpolator"), nil
+}
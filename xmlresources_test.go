@@ -0,0 +1,99 @@
+package apkparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreferenceScreens(t *testing.T) {
+	pref := func(typ, key, title string, children ...*testXmlElement) *testXmlElement {
+		return &testXmlElement{
+			name:     typ,
+			attrs:    []testXmlAttr{testStringAttr("key", key), testStringAttr("title", title)},
+			children: children,
+		}
+	}
+
+	a := openTestAPK(t, map[string][]byte{
+		"AndroidManifest.xml": testManifest(),
+		"res/xml/a_backup.xml": buildAxml(&testXmlElement{name: "full-backup-content", children: []*testXmlElement{
+			{name: "exclude", attrs: []testXmlAttr{testStringAttr("path", "secret")}},
+		}}),
+		"res/xml/b_broken.xml": []byte("not binary xml"),
+		"res/xml/c_settings.xml": buildAxml(pref("PreferenceScreen", "root", "Settings",
+			pref("CheckBoxPreference", "sync", "Sync", &testXmlElement{name: "intent"}),
+			pref("PreferenceCategory", "about", "About", pref("Preference", "version", "")),
+		)),
+		"res/xml/d_custom.xml":         buildAxml(pref("com.example.NotPreferenceScreen", "x", "X")),
+		"res/xml-v21/e_androidx.xml":   buildAxml(pref("androidx.preference.PreferenceScreen", "root", "Settings")),
+		"res/layout/f_preferences.xml": buildAxml(pref("PreferenceScreen", "layout", "Not in res/xml")),
+		"res/xml/g_truncated.xml":      buildAxml(pref("PreferenceScreen", "cut", "Cut"))[:40],
+	})
+
+	// res/xml-v21/ goes first in the zip.
+	expected := []PreferenceGroup{
+		{Type: "androidx.preference.PreferenceScreen", Key: "root", Title: "Settings"},
+		{Type: "PreferenceScreen", Key: "root", Title: "Settings", Children: []PreferenceGroup{
+			{Type: "CheckBoxPreference", Key: "sync", Title: "Sync"},
+			{Type: "PreferenceCategory", Key: "about", Title: "About", Children: []PreferenceGroup{
+				{Type: "Preference", Key: "version"},
+			}},
+		}},
+	}
+
+	res, err := a.PreferenceScreens()
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("got %+v, expected %+v", res, expected)
+	}
+
+	errs, ok := err.(MultiError)
+	if !ok || len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "res/xml/b_broken.xml: ") ||
+		!strings.HasPrefix(errs[1].Error(), "res/xml/g_truncated.xml: ") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestNavigationGraph(t *testing.T) {
+	ref := func(name string, id uint32) testXmlAttr {
+		return testXmlAttr{name: name, dataType: AttrTypeReference, data: id}
+	}
+
+	a := openTestAPK(t, map[string][]byte{
+		"AndroidManifest.xml": testManifest(),
+		"res/navigation/a_main.xml": buildAxml(&testXmlElement{name: "navigation", children: []*testXmlElement{
+			{name: "fragment", attrs: []testXmlAttr{ref("id", 0x7f080001), testStringAttr("name", ".HomeFragment"), testStringAttr("label", "Home")},
+				children: []*testXmlElement{
+					{name: "action", attrs: []testXmlAttr{ref("id", 0x7f080010), ref("destination", 0x7f080002)}},
+					{name: "argument", attrs: []testXmlAttr{testStringAttr("name", "user")}},
+				}},
+			{name: "navigation", attrs: []testXmlAttr{ref("id", 0x7f080003)}, children: []*testXmlElement{
+				{name: "activity", attrs: []testXmlAttr{ref("id", 0x7f080002), testStringAttr("name", "com.example.Details")}},
+			}},
+		}}),
+		"res/navigation/b_broken.xml": []byte("not binary xml"),
+		"res/navigation/c_other.xml": buildAxml(&testXmlElement{name: "navigation", children: []*testXmlElement{
+			{name: "dialog", attrs: []testXmlAttr{ref("id", 0x7f080004), testStringAttr("name", ".Confirm")}},
+		}}),
+		"res/xml/d_not_navigation.xml": buildAxml(&testXmlElement{name: "navigation", children: []*testXmlElement{
+			{name: "fragment", attrs: []testXmlAttr{ref("id", 0x7f080005)}},
+		}}),
+	})
+
+	expected := []NavDestination{
+		{ID: 0x7f080001, Label: "Home", ClassName: "com.example.HomeFragment", Actions: []NavAction{{ID: 0x7f080010, Destination: 0x7f080002}}},
+		{ID: 0x7f080003},
+		{ID: 0x7f080002, ClassName: "com.example.Details"},
+		{ID: 0x7f080004, ClassName: "com.example.Confirm"},
+	}
+
+	res, err := a.NavigationGraph()
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("got %+v, expected %+v", res, expected)
+	}
+
+	errs, ok := err.(MultiError)
+	if !ok || len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "res/navigation/b_broken.xml: ") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package apkparser
+
+import (
+	"encoding/xml"
+)
+
+// One element of a parsed binary XML document, as built by xmlTreeEncoder.
+type xmlElement struct {
+	Name     string
+	Attrs    []xml.Attr
+	Children []*xmlElement
+	Text     string
+
+	// Type and raw data of each of Attrs, nil for trees not parsed from binary XML.
+	attrTypes []AttrType
+	attrData  []uint32
+
+	parent *xmlElement
+}
+
+// ManifestEncoder which collects the tokens into a tree of xmlElements
+// instead of writing them out.
+type xmlTreeEncoder struct {
+	root  *xmlElement
+	stack []*xmlElement
+
+	// Raw attributes of the next StartElement.
+	attrTypes []AttrType
+	attrData  []uint32
+}
+
+func (e *xmlTreeEncoder) encodeRawAttrs(types []AttrType, data []uint32) {
+	e.attrTypes, e.attrData = types, data
+}
+
+func (e *xmlTreeEncoder) EncodeToken(t xml.Token) error {
+	switch tok := t.(type) {
+	case xml.StartElement:
+		el := &xmlElement{
+			Name:  tok.Name.Local,
+			Attrs: tok.Attr,
+		}
+		if len(e.attrTypes) == len(tok.Attr) {
+			el.attrTypes, el.attrData = e.attrTypes, e.attrData
+		}
+		e.attrTypes, e.attrData = nil, nil
+
+		if len(e.stack) != 0 {
+			el.parent = e.stack[len(e.stack)-1]
+			el.parent.Children = append(el.parent.Children, el)
+		} else if e.root == nil {
+			e.root = el
+		} else {
+			// Android only ever looks at the first top-level element.
+			return nil
+		}
+		e.stack = append(e.stack, el)
+	case xml.EndElement:
+		if len(e.stack) != 0 {
+			e.stack = e.stack[:len(e.stack)-1]
+		}
+	case xml.CharData:
+		if len(e.stack) != 0 {
+			e.stack[len(e.stack)-1].Text += string(tok)
+		}
+	}
+	return nil
+}
+
+func (e *xmlTreeEncoder) Flush() error {
+	return nil
+}
+
+// Returns value of the attribute with this local name. The namespace is ignored,
+// obfuscators like to mess with it and Android resolves the attributes by their ids anyway.
+func (e *xmlElement) attr(name string) (string, bool) {
+	for i := range e.Attrs {
+		if e.Attrs[i].Name.Local == name {
+			return e.Attrs[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Returns value of the attribute with this local name, or "" if it is not present.
+func (e *xmlElement) attrOrEmpty(name string) string {
+	val, _ := e.attr(name)
+	return val
+}
+
+// Returns direct children with this name.
+func (e *xmlElement) children(name string) []*xmlElement {
+	var res []*xmlElement
+	for _, c := range e.Children {
+		if c.Name == name {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Returns the first direct child with this name, or nil.
+func (e *xmlElement) child(name string) *xmlElement {
+	for _, c := range e.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Calls fn for this element and all its descendants, depth first.
+func (e *xmlElement) walk(fn func(el *xmlElement)) {
+	fn(e)
+	for _, c := range e.Children {
+		c.walk(fn)
+	}
+}
+
+// Returns all descendants (including this element) with this name.
+func (e *xmlElement) findAll(name string) []*xmlElement {
+	var res []*xmlElement
+	e.walk(func(el *xmlElement) {
+		if el.Name == name {
+			res = append(res, el)
+		}
+	})
+	return res
+}
+
+// Returns a deep copy of the element and its descendants, with parent set to parent.
+func (e *xmlElement) clone(parent *xmlElement) *xmlElement {
+	res := &xmlElement{
+		Name:   e.Name,
+		Attrs:  append([]xml.Attr(nil), e.Attrs...),
+		Text:   e.Text,
+		parent: parent,
+	}
+	if e.attrTypes != nil {
+		res.attrTypes = append([]AttrType(nil), e.attrTypes...)
+		res.attrData = append([]uint32(nil), e.attrData...)
+	}
+
+	for _, c := range e.Children {
+		res.Children = append(res.Children, c.clone(res))
+	}
+	return res
+}